@@ -0,0 +1,246 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// ErrNoAPReq is returned by InspectAPReq when the token does not hold a Kerberos AP-REQ
+var ErrNoAPReq = errors.New("token does not contain a Kerberos AP-REQ")
+
+// APReqInfo describes the unencrypted parts of the Kerberos AP-REQ sent by an initiator.  It
+// shows which service key the acceptor needs in order to decrypt the ticket, which is usually
+// what is wrong when an acceptor reports a wrong key version or a missing key for an enctype.
+type APReqInfo struct {
+	// Mech is the mechanism of the token, which is SPNEGO if the AP-REQ was wrapped in a
+	// SPNEGO token, or nil for a bare AP-REQ
+	Mech g.GssMech
+	// MutualRequired is set if the initiator requested mutual authentication
+	MutualRequired bool
+	// UseSessionKey is set if the ticket is encrypted in a session key (user-to-user)
+	UseSessionKey bool
+	// Realm is the realm of the service principal
+	Realm string
+	// SName is the service principal the ticket was issued for
+	SName PrincipalName
+	// TicketEnctype is the encryption type of the ticket, and of the service key needed to decrypt it
+	TicketEnctype int32
+	// TicketKvno is the version of the service key used to encrypt the ticket, if the KDC supplied it
+	TicketKvno *uint32
+	// AuthenticatorEnctype is the encryption type of the authenticator (the ticket session key)
+	AuthenticatorEnctype int32
+}
+
+// String describes the ticket presented by the initiator
+func (i *APReqInfo) String() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "initiator presented a ticket for %s@%s encrypted with %s", i.SName, i.Realm, EnctypeName(i.TicketEnctype))
+	if i.TicketKvno != nil {
+		fmt.Fprintf(&s, " kvno %d", *i.TicketKvno)
+	}
+
+	return s.String()
+}
+
+// AP options (RFC 4120 § 5.5.1)
+const (
+	apOptionUseSessionKey  = 1
+	apOptionMutualRequired = 2
+)
+
+// krb5 GSSAPI token identifier for AP-REQ tokens (RFC 1964 § 1.1)
+var krb5TokIDAPReq = []byte{0x01, 0x00}
+
+// InspectAPReq decodes the AP-REQ in an initial Kerberos context token, in a SPNEGO token
+// wrapping one, or a bare AP-REQ, without decrypting anything.  It returns ErrNoAPReq for
+// tokens that do not hold an AP-REQ, such as SPNEGO tokens that do not carry an optimistic
+// Kerberos token.
+//
+// When an acceptor's Continue fails, passing InspectAPReq the token that was rejected shows
+// which service key the initiator expected the acceptor to have.
+func InspectAPReq(token []byte) (*APReqInfo, error) {
+	if len(token) == 0 {
+		return nil, ErrNoAPReq
+	}
+
+	var mech g.GssMech
+	switch token[0] {
+	default:
+		return nil, ErrNoAPReq
+	case 0x6e: // [APPLICATION 14]
+	case 0xa1: // SPNEGO negTokenResp, when the Kerberos token follows a mech negotiation round
+		responseToken, err := spnegoResponseToken(token)
+		if err != nil {
+			return nil, err
+		}
+		info, err := InspectAPReq(responseToken)
+		if err != nil {
+			return nil, err
+		}
+		info.Mech = g.GSS_MECH_SPNEGO
+		return info, nil
+	case tokenFramingTag:
+		hdr, err := ParseTokenHeader(token)
+		if err != nil {
+			return nil, err
+		}
+		mech = hdr.Mech
+
+		switch hdr.Mech {
+		default:
+			return nil, ErrNoAPReq
+		case g.GSS_MECH_SPNEGO:
+			mechToken, err := spnegoMechToken(hdr.InnerToken)
+			if err != nil {
+				return nil, err
+			}
+			info, err := InspectAPReq(mechToken)
+			if err != nil {
+				return nil, err
+			}
+			info.Mech = g.GSS_MECH_SPNEGO
+			return info, nil
+		case g.GSS_MECH_KRB5:
+			if len(hdr.InnerToken) < 2 || !slices.Equal(hdr.InnerToken[:2], krb5TokIDAPReq) {
+				return nil, ErrNoAPReq
+			}
+			token = hdr.InnerToken[2:]
+		}
+	}
+
+	info, err := parseAPReq(token)
+	if err != nil {
+		return nil, err
+	}
+	info.Mech = mech
+
+	return info, nil
+}
+
+// spnegoMechToken extracts the mechToken from a SPNEGO negTokenInit (RFC 4178 § 4.2.1).
+// It returns nil if there is no mechToken.
+func spnegoMechToken(token []byte) ([]byte, error) {
+	v, _, err := readDER(token)
+	if err != nil {
+		return nil, err
+	}
+	if v.class != derClassContext || v.tag != 0 {
+		// negTokenInit2 and friends are not supported
+		return nil, ErrNoAPReq
+	}
+
+	seq, err := derSequence(v.body)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := derFields(seq)
+	if err != nil {
+		return nil, err
+	}
+
+	f, ok := fields[2]
+	if !ok {
+		return nil, nil
+	}
+
+	return derOctetString(f)
+}
+
+func parseAPReq(b []byte) (*APReqInfo, error) {
+	app, _, err := expectDER(b, derClassApplication, 14)
+	if err != nil {
+		return nil, err
+	}
+	seq, err := derSequence(app.body)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := derFields(seq)
+	if err != nil {
+		return nil, err
+	}
+
+	msgType, err := derInt32(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	if msgType != 14 {
+		return nil, fmt.Errorf("%w: AP-REQ has message type %d", ErrMalformedToken, msgType)
+	}
+
+	ret := &APReqInfo{}
+
+	var apOptions asn1.BitString
+	if _, err := asn1.Unmarshal(fields[2], &apOptions); err != nil {
+		return nil, fmt.Errorf("%w: bad AP options: %w", ErrMalformedToken, err)
+	}
+	ret.UseSessionKey = apOptions.At(apOptionUseSessionKey) == 1
+	ret.MutualRequired = apOptions.At(apOptionMutualRequired) == 1
+
+	// Ticket
+	tkt, _, err := expectDER(fields[3], derClassApplication, 1)
+	if err != nil {
+		return nil, err
+	}
+	tktSeq, err := derSequence(tkt.body)
+	if err != nil {
+		return nil, err
+	}
+	tktFields, err := derFields(tktSeq)
+	if err != nil {
+		return nil, err
+	}
+
+	if ret.Realm, err = derKerberosString(tktFields[1]); err != nil {
+		return nil, err
+	}
+	if ret.SName, err = parsePrincipalName(tktFields[2]); err != nil {
+		return nil, err
+	}
+	if ret.TicketEnctype, ret.TicketKvno, err = parseEncryptedDataHeader(tktFields[3]); err != nil {
+		return nil, err
+	}
+
+	// Authenticator
+	if ret.AuthenticatorEnctype, _, err = parseEncryptedDataHeader(fields[4]); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// parseEncryptedDataHeader returns the etype and kvno of an EncryptedData (RFC 4120 § 5.2.9),
+// leaving the cipher text alone
+func parseEncryptedDataHeader(b []byte) (etype int32, kvno *uint32, err error) {
+	seq, err := derSequence(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	fields, err := derFields(seq)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if etype, err = derInt32(fields[0]); err != nil {
+		return 0, nil, err
+	}
+
+	if f, ok := fields[1]; ok {
+		var k int64
+		if _, err := asn1.Unmarshal(f, &k); err != nil || k < -0x80000000 || k > 0xffffffff {
+			return 0, nil, fmt.Errorf("%w: bad kvno", ErrMalformedToken)
+		}
+		// some implementations encode large key versions as a negative Int32
+		v := uint32(k)
+		kvno = &v
+	}
+
+	return etype, kvno, nil
+}
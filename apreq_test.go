@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"testing"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+func mkTestEncryptedData(etype int, kvno *int) []byte {
+	fields := [][]byte{derCtx(0, derInt(etype))}
+	if kvno != nil {
+		fields = append(fields, derCtx(1, derInt(*kvno)))
+	}
+	fields = append(fields, derCtx(2, derOctets([]byte{0xde, 0xad, 0xbe, 0xef})))
+
+	return derSeq(fields...)
+}
+
+func mkTestAPReq(apOptions []byte, etype int, kvno *int) []byte {
+	ticket := derTLV(0x61, derSeq(
+		derCtx(0, derInt(5)),
+		derCtx(1, derGenStr("GOLANG-AUTH.IO")),
+		derCtx(2, derPrincipal(3, "rack", "foo.golang-auth.io")),
+		derCtx(3, mkTestEncryptedData(etype, kvno)),
+	))
+
+	return derTLV(0x6e, derSeq(
+		derCtx(0, derInt(5)),
+		derCtx(1, derInt(14)),
+		derCtx(2, apOptions),
+		derCtx(3, ticket),
+		derCtx(4, mkTestEncryptedData(EnctypeAes128CtsHmacSha196, nil)),
+	))
+}
+
+// AP options BIT STRINGs
+var (
+	testAPOptsNone   = []byte{0x03, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00}
+	testAPOptsMutual = []byte{0x03, 0x05, 0x00, 0x20, 0x00, 0x00, 0x00}
+	testAPOptsU2U    = []byte{0x03, 0x05, 0x00, 0x40, 0x00, 0x00, 0x00}
+)
+
+func TestInspectAPReq(t *testing.T) {
+	kvno := 3
+	raw := mkTestAPReq(testAPOptsMutual, EnctypeAes256CtsHmacSha196, &kvno)
+	krb5Token := derFramed(g.GSS_MECH_KRB5.Oid(), []byte{0x01, 0x00}, raw)
+
+	tests := []struct {
+		name  string
+		token []byte
+		mech  g.GssMech
+	}{
+		{"bare", raw, nil},
+		{"framed", krb5Token, g.GSS_MECH_KRB5},
+		{"spnego-init", derFramed(g.GSS_MECH_SPNEGO.Oid(), derCtx(0, derSeq(
+			derCtx(0, derSeq(derTLV(0x06, g.GSS_MECH_KRB5.Oid()))),
+			derCtx(2, derOctets(krb5Token)),
+		))), g.GSS_MECH_SPNEGO},
+		{"spnego-resp", derCtx(1, derSeq(derCtx(0, []byte{0x0a, 0x01, 0x01}), derCtx(2, derOctets(krb5Token)))), g.GSS_MECH_SPNEGO},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := NewAssert(t)
+
+			info, err := InspectAPReq(tt.token)
+			assert.NoErrorFatal(err)
+
+			assert.Equal(tt.mech, info.Mech)
+			assert.True(info.MutualRequired)
+			assert.False(info.UseSessionKey)
+			assert.Equal("GOLANG-AUTH.IO", info.Realm)
+			assert.Equal("rack/foo.golang-auth.io", info.SName.String())
+			assert.Equal(int32(EnctypeAes256CtsHmacSha196), info.TicketEnctype)
+			assert.NotNil(info.TicketKvno)
+			assert.Equal(uint32(3), *info.TicketKvno)
+			assert.Equal(int32(EnctypeAes128CtsHmacSha196), info.AuthenticatorEnctype)
+			assert.Equal("initiator presented a ticket for rack/foo.golang-auth.io@GOLANG-AUTH.IO encrypted with aes256-cts-hmac-sha1-96 kvno 3", info.String())
+		})
+	}
+}
+
+func TestInspectAPReqOptions(t *testing.T) {
+	assert := NewAssert(t)
+
+	// no kvno, no options
+	info, err := InspectAPReq(mkTestAPReq(testAPOptsNone, EnctypeArcfourHmac, nil))
+	assert.NoErrorFatal(err)
+	assert.False(info.MutualRequired)
+	assert.False(info.UseSessionKey)
+	assert.Nil(info.TicketKvno)
+	assert.Equal("initiator presented a ticket for rack/foo.golang-auth.io@GOLANG-AUTH.IO encrypted with arcfour-hmac", info.String())
+
+	// user-to-user
+	info, err = InspectAPReq(mkTestAPReq(testAPOptsU2U, EnctypeAes128CtsHmacSha196, nil))
+	assert.NoErrorFatal(err)
+	assert.True(info.UseSessionKey)
+	assert.False(info.MutualRequired)
+
+	// large key version encoded as a negative Int32
+	kvno := -2147483647
+	info, err = InspectAPReq(mkTestAPReq(testAPOptsNone, EnctypeAes128CtsHmacSha196, &kvno))
+	assert.NoErrorFatal(err)
+	assert.Equal(uint32(0x80000001), *info.TicketKvno)
+}
+
+func TestInspectAPReqNoAPReq(t *testing.T) {
+	raw := mkTestAPReq(testAPOptsNone, EnctypeAes128CtsHmacSha196, nil)
+
+	tests := []struct {
+		name  string
+		token []byte
+	}{
+		{"empty", nil},
+		{"unknown", []byte{0x30, 0x00}},
+		{"krb-error", derFramed(g.GSS_MECH_KRB5.Oid(), []byte{0x03, 0x00}, mkTestKrbError(41, nil))},
+		{"other-mech", derFramed(g.Oid{0x2b, 0x06, 0x01, 0x04, 0x01, 0x82, 0x37, 0x02, 0x02, 0x0a}, raw)},
+		{"spnego-no-mech-token", derFramed(g.GSS_MECH_SPNEGO.Oid(), derCtx(0, derSeq(
+			derCtx(0, derSeq(derTLV(0x06, g.GSS_MECH_KRB5.Oid()))),
+		)))},
+		{"spnego-resp-no-token", derCtx(1, derSeq(derCtx(0, []byte{0x0a, 0x01, 0x00})))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := NewAssert(t)
+
+			_, err := InspectAPReq(tt.token)
+			assert.ErrorIs(err, ErrNoAPReq)
+		})
+	}
+}
+
+func TestInspectAPReqMalformed(t *testing.T) {
+	assert := NewAssert(t)
+
+	raw := mkTestAPReq(testAPOptsNone, EnctypeAes128CtsHmacSha196, nil)
+
+	_, err := InspectAPReq(raw[:len(raw)-4])
+	assert.ErrorIs(err, ErrMalformedToken)
+
+	// wrong message type
+	bad := derTLV(0x6e, derSeq(derCtx(0, derInt(5)), derCtx(1, derInt(30))))
+	_, err = InspectAPReq(bad)
+	assert.ErrorIs(err, ErrMalformedToken)
+
+	// missing ticket
+	bad = derTLV(0x6e, derSeq(derCtx(0, derInt(5)), derCtx(1, derInt(14)), derCtx(2, testAPOptsNone)))
+	_, err = InspectAPReq(bad)
+	assert.ErrorIs(err, ErrMalformedToken)
+}
+
+func TestEnctypeName(t *testing.T) {
+	assert := NewAssert(t)
+
+	assert.Equal("aes256-cts-hmac-sha1-96", EnctypeName(EnctypeAes256CtsHmacSha196))
+	assert.Equal("aes256-cts-hmac-sha384-192", EnctypeName(EnctypeAes256CtsHmacSha384192))
+	assert.Equal("99", EnctypeName(99))
+}
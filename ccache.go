@@ -0,0 +1,277 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrMalformedCCache is returned when a credentials cache file cannot be decoded
+var ErrMalformedCCache = errors.New("malformed credentials cache")
+
+// Principal is a Kerberos principal name with its realm
+type Principal struct {
+	PrincipalName
+	Realm string
+}
+
+// String returns the principal name in the usual name/instance@REALM form
+func (p Principal) String() string {
+	return p.PrincipalName.String() + "@" + p.Realm
+}
+
+// TicketFlags holds the flags of a Kerberos ticket (RFC 4120 § 5.3), in the representation
+// used by the krb5 library and credentials caches
+type TicketFlags uint32
+
+// Ticket flags
+const (
+	TicketFlagForwardable          TicketFlags = 0x40000000
+	TicketFlagForwarded            TicketFlags = 0x20000000
+	TicketFlagProxiable            TicketFlags = 0x10000000
+	TicketFlagProxy                TicketFlags = 0x08000000
+	TicketFlagMayPostdate          TicketFlags = 0x04000000
+	TicketFlagPostdated            TicketFlags = 0x02000000
+	TicketFlagInvalid              TicketFlags = 0x01000000
+	TicketFlagRenewable            TicketFlags = 0x00800000
+	TicketFlagInitial              TicketFlags = 0x00400000
+	TicketFlagPreAuthent           TicketFlags = 0x00200000
+	TicketFlagHWAuthent            TicketFlags = 0x00100000
+	TicketFlagTransitPolicyChecked TicketFlags = 0x00080000
+	TicketFlagOKAsDelegate         TicketFlags = 0x00040000
+	TicketFlagEncPARep             TicketFlags = 0x00010000
+	TicketFlagAnonymous            TicketFlags = 0x00008000
+)
+
+// ticket flags in the order and with the letters used by klist
+var ticketFlagLetters = []struct {
+	flag   TicketFlags
+	letter byte
+}{
+	{TicketFlagForwardable, 'F'},
+	{TicketFlagForwarded, 'f'},
+	{TicketFlagProxiable, 'P'},
+	{TicketFlagProxy, 'p'},
+	{TicketFlagMayPostdate, 'D'},
+	{TicketFlagPostdated, 'd'},
+	{TicketFlagRenewable, 'R'},
+	{TicketFlagInitial, 'I'},
+	{TicketFlagInvalid, 'i'},
+	{TicketFlagHWAuthent, 'H'},
+	{TicketFlagPreAuthent, 'A'},
+	{TicketFlagTransitPolicyChecked, 'T'},
+	{TicketFlagOKAsDelegate, 'O'},
+	{TicketFlagAnonymous, 'a'},
+}
+
+// String returns the flags using the same letters as klist -f
+func (f TicketFlags) String() string {
+	var s strings.Builder
+	for _, fl := range ticketFlagLetters {
+		if f&fl.flag != 0 {
+			s.WriteByte(fl.letter)
+		}
+	}
+
+	return s.String()
+}
+
+// CCacheCredential is a ticket held in a credentials cache.  The session key and the ticket
+// itself are not retained.
+type CCacheCredential struct {
+	Client        Principal
+	Server        Principal
+	Enctype       int32 // session key encryption type
+	TicketEnctype int32 // encryption type of the ticket, or zero if the ticket could not be decoded
+	AuthTime      time.Time
+	StartTime     time.Time // zero if the starttime field was not set, in which case AuthTime applies
+	EndTime       time.Time
+	RenewTill     time.Time // zero if the ticket is not renewable
+	Flags         TicketFlags
+	IsSKey        bool // set for user-to-user tickets
+}
+
+// CCacheConfig is a configuration entry stored in a credentials cache by the krb5 library
+// (krb5_cc_set_config), such as the fast_avail or pa_type entries.
+type CCacheConfig struct {
+	Key       string
+	Principal string // the principal the entry relates to, if any
+	Value     []byte
+}
+
+// CCache is the decoded contents of a FILE credentials cache, giving the same view of the
+// cache as klist
+type CCache struct {
+	Version          int           // 3 or 4
+	KDCTimeOffset    time.Duration // the client clock offset from the KDC, if recorded
+	DefaultPrincipal Principal
+	Credentials      []CCacheCredential
+	Config           []CCacheConfig
+}
+
+// ReadCCache reads and decodes a credentials cache file.  The name may be prefixed with the
+// FILE: credentials cache type; other types cannot be read directly.
+func ReadCCache(name string) (*CCache, error) {
+	cctype, path, found := strings.Cut(name, ":")
+	switch {
+	case !found || strings.HasPrefix(name, "/"):
+		path = name
+	case cctype == "FILE":
+	default:
+		return nil, fmt.Errorf("credentials cache type %s is not supported", cctype)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseCCache(data)
+}
+
+// realm and first component of the server principal of configuration entries
+const (
+	ccacheConfRealm = "X-CACHECONF:"
+	ccacheConfName  = "krb5_ccache_conf_data"
+)
+
+// v4 header tags
+const ccacheTagKDCOffset = 1
+
+// ParseCCache decodes the contents of a FILE credentials cache in version 3 (0x0503) or
+// version 4 (0x0504) format, as written by MIT and Heimdal.
+func ParseCCache(data []byte) (*CCache, error) {
+	if len(data) < 2 || data[0] != 0x05 {
+		return nil, fmt.Errorf("%w: not a credentials cache file", ErrMalformedCCache)
+	}
+
+	cc := &CCache{}
+	switch data[1] {
+	default:
+		return nil, fmt.Errorf("%w: unsupported credentials cache version 0x05%02x", ErrMalformedCCache, data[1])
+	case 0x03:
+		cc.Version = 3
+	case 0x04:
+		cc.Version = 4
+	}
+
+	r := &binReader{b: data[2:], order: binary.BigEndian, malformed: ErrMalformedCCache}
+
+	if cc.Version == 4 {
+		hdr := &binReader{b: r.data16(), order: binary.BigEndian, malformed: ErrMalformedCCache}
+		for len(hdr.b) > 0 && hdr.err == nil {
+			tag := hdr.uint16()
+			field := hdr.data16()
+			if tag == ccacheTagKDCOffset && len(field) == 8 {
+				secs := int32(binary.BigEndian.Uint32(field))
+				usecs := int32(binary.BigEndian.Uint32(field[4:]))
+				cc.KDCTimeOffset = time.Duration(secs)*time.Second + time.Duration(usecs)*time.Microsecond
+			}
+		}
+		if hdr.err != nil {
+			return nil, hdr.err
+		}
+	}
+
+	cc.DefaultPrincipal = readCCachePrincipal(r)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	for len(r.b) > 0 {
+		cred, conf, err := readCCacheCredential(r, cc.Version)
+		switch {
+		case err != nil:
+			return nil, err
+		case conf != nil:
+			cc.Config = append(cc.Config, *conf)
+		default:
+			cc.Credentials = append(cc.Credentials, cred)
+		}
+	}
+
+	return cc, nil
+}
+
+func readCCachePrincipal(r *binReader) Principal {
+	p := Principal{}
+	p.NameType = int32(r.uint32())
+	numComponents := int(r.uint32())
+	p.Realm = string(r.data32())
+	for i := 0; i < numComponents && r.err == nil; i++ {
+		p.NameString = append(p.NameString, string(r.data32()))
+	}
+
+	return p
+}
+
+// readCCacheCredential reads one credential, returning it as a configuration entry if it is one
+func readCCacheCredential(r *binReader, version int) (CCacheCredential, *CCacheConfig, error) {
+	cred := CCacheCredential{}
+
+	cred.Client = readCCachePrincipal(r)
+	cred.Server = readCCachePrincipal(r)
+
+	cred.Enctype = int32(int16(r.uint16()))
+	if version == 3 {
+		// version 3 stores the enctype twice
+		_ = r.uint16()
+	}
+	_ = r.data32() // session key
+
+	cred.AuthTime = ccacheTime(r.uint32())
+	cred.StartTime = ccacheTime(r.uint32())
+	cred.EndTime = ccacheTime(r.uint32())
+	cred.RenewTill = ccacheTime(r.uint32())
+	cred.IsSKey = r.uint8() != 0
+	cred.Flags = TicketFlags(r.uint32())
+
+	// addresses and authorization data
+	for range 2 {
+		count := r.uint32()
+		for i := uint32(0); i < count && r.err == nil; i++ {
+			_ = r.uint16()
+			_ = r.data32()
+		}
+	}
+
+	ticket := r.data32()
+	_ = r.data32() // second ticket
+	if r.err != nil {
+		return cred, nil, r.err
+	}
+
+	if cred.Server.Realm == ccacheConfRealm && len(cred.Server.NameString) >= 2 && cred.Server.NameString[0] == ccacheConfName {
+		conf := &CCacheConfig{
+			Key:   cred.Server.NameString[1],
+			Value: ticket,
+		}
+		if len(cred.Server.NameString) > 2 {
+			conf.Principal = cred.Server.NameString[2]
+		}
+		return cred, conf, nil
+	}
+
+	// Ticket ::= [APPLICATION 1] SEQUENCE { ... enc-part [3] EncryptedData }
+	if tkt, _, err := expectDER(ticket, derClassApplication, 1); err == nil {
+		if seq, err := derSequence(tkt.body); err == nil {
+			if fields, err := derFields(seq); err == nil {
+				cred.TicketEnctype, _, _ = parseEncryptedDataHeader(fields[3])
+			}
+		}
+	}
+
+	return cred, nil, nil
+}
+
+func ccacheTime(t uint32) time.Time {
+	if t == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(t), 0)
+}
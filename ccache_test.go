@@ -0,0 +1,173 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseCCache(t *testing.T) {
+	assert := NewAssert(t)
+
+	cc, err := ParseCCache(decodeTestVector(t, ccdata))
+	assert.NoErrorFatal(err)
+
+	assert.Equal(4, cc.Version)
+	assert.Equal(time.Duration(0), cc.KDCTimeOffset)
+	assert.Equal("robot@GOLANG-AUTH.IO", cc.DefaultPrincipal.String())
+	assert.Equal(int32(1), cc.DefaultPrincipal.NameType)
+
+	assert.Len(cc.Credentials, 2)
+	tgt := cc.Credentials[0]
+	assert.Equal("robot@GOLANG-AUTH.IO", tgt.Client.String())
+	assert.Equal("krbtgt/GOLANG-AUTH.IO@GOLANG-AUTH.IO", tgt.Server.String())
+	assert.Equal(int32(EnctypeAes256CtsHmacSha196), tgt.Enctype)
+	assert.Equal(int32(EnctypeAes256CtsHmacSha196), tgt.TicketEnctype)
+	assert.Equal(time.Unix(1759675375, 0), tgt.AuthTime)
+	assert.Equal(time.Unix(1988150404, 0), tgt.EndTime)
+	assert.True(tgt.RenewTill.IsZero())
+	assert.NotZero(tgt.Flags & TicketFlagInitial)
+	assert.Equal("I", tgt.Flags.String())
+	assert.False(tgt.IsSKey)
+
+	svc := cc.Credentials[1]
+	assert.Equal("rack/foo.golang-auth.io@GOLANG-AUTH.IO", svc.Server.String())
+	assert.Zero(svc.Flags & TicketFlagInitial)
+
+	assert.Equal([]CCacheConfig{{
+		Key:       "fast_avail",
+		Principal: "krbtgt/GOLANG-AUTH.IO@GOLANG-AUTH.IO",
+		Value:     []byte("yes"),
+	}}, cc.Config)
+}
+
+// mkTestCCachePrincipal encodes a principal in credentials cache format
+func mkTestCCachePrincipal(realm string, names ...string) []byte {
+	var b []byte
+	b = binary.BigEndian.AppendUint32(b, 1)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(names)))
+	for _, s := range append([]string{realm}, names...) {
+		b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+		b = append(b, s...)
+	}
+	return b
+}
+
+// mkTestCCacheV3 builds a version 3 credentials cache holding one ticket
+func mkTestCCacheV3() []byte {
+	b := []byte{0x05, 0x03}
+	b = append(b, mkTestCCachePrincipal("EXAMPLE.COM", "user")...)
+
+	b = append(b, mkTestCCachePrincipal("EXAMPLE.COM", "user")...)
+	b = append(b, mkTestCCachePrincipal("EXAMPLE.COM", "host", "server.example.com")...)
+	b = binary.BigEndian.AppendUint16(b, EnctypeAes128CtsHmacSha196)
+	b = binary.BigEndian.AppendUint16(b, EnctypeAes128CtsHmacSha196) // repeated in version 3
+	b = binary.BigEndian.AppendUint32(b, 16)
+	b = append(b, make([]byte, 16)...)
+	for _, t := range []uint32{1700000000, 0, 1700036000, 1700600000} {
+		b = binary.BigEndian.AppendUint32(b, t)
+	}
+	b = append(b, 0)
+	b = binary.BigEndian.AppendUint32(b, uint32(TicketFlagForwardable|TicketFlagRenewable))
+	b = binary.BigEndian.AppendUint32(b, 1) // one address
+	b = binary.BigEndian.AppendUint16(b, 2)
+	b = binary.BigEndian.AppendUint32(b, 4)
+	b = append(b, 192, 0, 2, 1)
+	b = binary.BigEndian.AppendUint32(b, 0) // no authdata
+	b = binary.BigEndian.AppendUint32(b, 4) // not a real ticket
+	b = append(b, 0xde, 0xad, 0xbe, 0xef)
+	b = binary.BigEndian.AppendUint32(b, 0)
+
+	return b
+}
+
+func TestParseCCacheV3(t *testing.T) {
+	assert := NewAssert(t)
+
+	cc, err := ParseCCache(mkTestCCacheV3())
+	assert.NoErrorFatal(err)
+
+	assert.Equal(3, cc.Version)
+	assert.Equal("user@EXAMPLE.COM", cc.DefaultPrincipal.String())
+	assert.Len(cc.Credentials, 1)
+	assert.Empty(cc.Config)
+
+	cred := cc.Credentials[0]
+	assert.Equal("host/server.example.com@EXAMPLE.COM", cred.Server.String())
+	assert.Equal(int32(EnctypeAes128CtsHmacSha196), cred.Enctype)
+	assert.Equal(int32(0), cred.TicketEnctype)
+	assert.Equal(time.Unix(1700000000, 0), cred.AuthTime)
+	assert.True(cred.StartTime.IsZero())
+	assert.Equal(time.Unix(1700036000, 0), cred.EndTime)
+	assert.Equal(time.Unix(1700600000, 0), cred.RenewTill)
+	assert.Equal("FR", cred.Flags.String())
+}
+
+func TestParseCCacheKDCOffset(t *testing.T) {
+	assert := NewAssert(t)
+
+	data := decodeTestVector(t, ccdata)
+	// the test cache has a KDC offset header field of zero
+	assert.Equal([]byte{0x00, 0x0c, 0x00, 0x01, 0x00, 0x08}, data[2:8])
+	binary.BigEndian.PutUint32(data[8:], uint32(0xffffffff)) // -1s
+	binary.BigEndian.PutUint32(data[12:], uint32(500000))    // 0.5s
+
+	cc, err := ParseCCache(data)
+	assert.NoErrorFatal(err)
+	assert.Equal(-500*time.Millisecond, cc.KDCTimeOffset)
+}
+
+func TestParseCCacheMalformed(t *testing.T) {
+	good := decodeTestVector(t, ccdata)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not-ccache", []byte("hello")},
+		{"version", []byte{0x05, 0x02}},
+		{"truncated-header", good[:5]},
+		{"truncated-principal", good[:20]},
+		{"truncated-credential", good[:len(good)-4]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := NewAssert(t)
+
+			_, err := ParseCCache(tt.data)
+			assert.ErrorIs(err, ErrMalformedCCache)
+		})
+	}
+}
+
+func TestReadCCache(t *testing.T) {
+	assert := NewAssert(t)
+
+	fn := filepath.Join(t.TempDir(), "ccache")
+	assert.NoErrorFatal(os.WriteFile(fn, decodeTestVector(t, ccdata), 0600))
+
+	for _, name := range []string{fn, "FILE:" + fn} {
+		cc, err := ReadCCache(name)
+		assert.NoError(err)
+		assert.Equal("robot@GOLANG-AUTH.IO", cc.DefaultPrincipal.String())
+	}
+
+	_, err := ReadCCache("KCM:1000")
+	assert.Error(err)
+	_, err = ReadCCache(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(err, os.ErrNotExist)
+}
+
+func TestTicketFlagsString(t *testing.T) {
+	assert := NewAssert(t)
+
+	assert.Equal("", TicketFlags(0).String())
+	assert.Equal("FRIA", (TicketFlagForwardable | TicketFlagRenewable | TicketFlagInitial | TicketFlagPreAuthent).String())
+	assert.Equal("fTOa", (TicketFlagForwarded | TicketFlagTransitPolicyChecked | TicketFlagOKAsDelegate | TicketFlagAnonymous | TicketFlagEncPARep).String())
+}
@@ -28,7 +28,8 @@ func TestMain(m *testing.M) {
 	ta = mkTestAssets()
 	defer ta.Free()
 
-	fmt.Fprintf(os.Stderr, "isHeimdalBefore7: %v, isHeimdalAfter7: %v, isHeimdalFreeBSD: %v, isMacGssapi: %v\n", isHeimdalBefore7(), isHeimdalAfter7(), isHeimdalFreeBSD(), isMacGssapi())
+	info := ta.lib.(*provider).Info()
+	fmt.Fprintf(os.Stderr, "family: %s %s, library: %s, quirks: %v, %s\n", info.Family, info.Version, info.Library, info.Quirks, info.ThreadSafety)
 
 	ta.useAsset(nil, testCfg1)
 
@@ -346,3 +347,72 @@ func writeKrb5Confs(tmpDir string) (f1, f2 string, err error) {
 
 	return
 }
+
+// establishContexts sets up a Kerberos context between the test initiator and the rack
+// service, in the same way as TestSecContextEstablishment.  Any options are passed to
+// InitSecContext.  The contexts are deleted when the test finishes.
+func establishContexts(t *testing.T, flags g.ContextFlag, opts ...g.InitSecContextOption) (initiator, acceptor *SecContext) {
+	ta.useAsset(t, testCredCache|testKeytabRack)
+
+	initiator, acceptor, _ = establishContextsWith(t, "rack@foo.golang-auth.io", contextOptions{
+		initiator: append([]g.InitSecContextOption{g.WithInitiatorFlags(flags)}, opts...),
+	})
+	return initiator, acceptor
+}
+
+// contextOptions configure the contexts made by establishContextsWith
+type contextOptions struct {
+	initiator []g.InitSecContextOption
+	acceptor  []g.AcceptSecContextOption
+
+	// asInitiator and asAcceptor are called, if set, before each call into the library for
+	// that side, for example to give each its own krb5.conf
+	asInitiator func()
+	asAcceptor  func()
+}
+
+// establishContextsWith sets up a context to the host based service target using the
+// current credentials and keytab, and returns the number of tokens the initiator sent.  The
+// contexts are deleted when the test finishes.
+func establishContextsWith(t *testing.T, target string, o contextOptions) (initiator, acceptor *SecContext, rounds int) {
+	assert := NewAssert(t)
+	if o.asInitiator == nil {
+		o.asInitiator = func() {}
+	}
+	if o.asAcceptor == nil {
+		o.asAcceptor = func() {}
+	}
+
+	o.asInitiator()
+	name, err := ta.lib.ImportName(target, g.GSS_NT_HOSTBASED_SERVICE)
+	assert.NoErrorFatal(err)
+	defer name.Release() //nolint:errcheck
+
+	secCtxInitiator, err := ta.lib.InitSecContext(name, o.initiator...)
+	assert.NoErrorFatal(err)
+	t.Cleanup(func() { _, _ = secCtxInitiator.Delete() })
+
+	o.asAcceptor()
+	secCtxAcceptor, err := ta.lib.AcceptSecContext(o.acceptor...)
+	assert.NoErrorFatal(err)
+	t.Cleanup(func() { _, _ = secCtxAcceptor.Delete() })
+
+	var initiatorTok, acceptorTok []byte
+	for secCtxInitiator.ContinueNeeded() || secCtxAcceptor.ContinueNeeded() {
+		if rounds++; rounds > 10 {
+			t.Fatalf("context not established after %d rounds", rounds-1)
+		}
+
+		o.asInitiator()
+		acceptorTok, _, err = secCtxInitiator.Continue(initiatorTok)
+		assert.NoErrorFatal(err)
+
+		if len(acceptorTok) > 0 {
+			o.asAcceptor()
+			initiatorTok, _, err = secCtxAcceptor.Continue(acceptorTok)
+			assert.NoErrorFatal(err)
+		}
+	}
+
+	return secCtxInitiator.(*SecContext), secCtxAcceptor.(*SecContext), rounds
+}
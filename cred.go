@@ -9,6 +9,7 @@ package gssapi
 import "C"
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"runtime"
@@ -23,6 +24,9 @@ type Credential struct {
 	usage g.CredUsage
 
 	isFromNoName bool
+
+	// private credentials cache that is destroyed along with the last credential using it
+	ownedCCache *ownedCCache
 }
 
 func hasDuplicateCred() bool {
@@ -64,6 +68,58 @@ func (provider) AcquireCredential(name g.GssName, mechs []g.GssMech, usage g.Cre
 	return cred, nil
 }
 
+// AcquireCredentialContext is like AcquireCredential but returns ctx.Err() if ctx is cancelled or
+// its deadline passes before the library returns.  The underlying call cannot be interrupted; it is
+// left to finish in the background and any credential it acquires is released.
+func (p provider) AcquireCredentialContext(ctx context.Context, name g.GssName, mechs []g.GssMech, usage g.CredUsage, lifetime *g.GssLifetime) (g.Credential, error) {
+	return acquireCancelable(ctx, name, func(name g.GssName) (g.Credential, error) {
+		return p.AcquireCredential(name, mechs, usage, lifetime)
+	})
+}
+
+// acquireCancelable runs acquire via runCancelable.  The caller may release name as soon as we
+// return, so acquire is given its own copy.
+func acquireCancelable(ctx context.Context, name g.GssName, acquire func(g.GssName) (g.Credential, error)) (g.Credential, error) {
+	var workName g.GssName
+	if name != nil {
+		lName, ok := name.(*GssName)
+		if !ok {
+			return nil, fmt.Errorf("bad name type %T, %w", name, g.ErrBadName)
+		}
+
+		dup, err := lName.Duplicate()
+		if err != nil {
+			return nil, err
+		}
+		workName = dup
+	}
+
+	type result struct {
+		cred g.Credential
+		err  error
+	}
+
+	ret, started, err := runCancelable(ctx, func() result {
+		cred, err := acquire(workName)
+		if workName != nil {
+			_ = workName.Release()
+		}
+		return result{cred, err}
+	}, func(ret result) {
+		if ret.cred != nil {
+			_ = ret.cred.Release()
+		}
+	})
+	if !started && workName != nil {
+		_ = workName.Release()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ret.cred, ret.err
+}
+
 func (c *Credential) Release() error {
 	if c == nil || c.id == nil {
 		return nil
@@ -71,7 +127,12 @@ func (c *Credential) Release() error {
 	var minor C.OM_uint32
 	major := C.gss_release_cred(&minor, &c.id)
 	c.id = nil
-	return makeStatus(major, minor)
+	err := makeStatus(major, minor)
+
+	err = errors.Join(err, c.ownedCCache.release())
+	c.ownedCCache = nil
+
+	return err
 }
 
 func (c *Credential) Inquire() (*g.CredInfo, error) {
@@ -127,16 +188,11 @@ func (c *Credential) Inquire() (*g.CredInfo, error) {
 
 	actualMechOids := oidsFromGssOidSet(cMechs)
 	for _, oid := range actualMechOids {
-		mech, err := g.MechFromOid(oid)
-		switch {
-		default:
-			ret.Mechs = append(ret.Mechs, mech)
-		case errors.Is(err, g.ErrBadMech):
-			// warn
-			continue
-		case err != nil:
+		mech, err := mechFromOid(oid)
+		if err != nil {
 			return nil, err
 		}
+		ret.Mechs = append(ret.Mechs, mech)
 	}
 
 	return ret, nil
@@ -237,6 +293,7 @@ func (c *Credential) Add(name g.GssName, mech g.GssMech, usage g.CredUsage, init
 			id:           cCredOut,
 			usage:        usage,
 			isFromNoName: cGssName == C.GSS_C_NO_NAME,
+			ownedCCache:  c.ownedCCache.ref(),
 		}, nil
 	}
 }
@@ -43,7 +43,7 @@ OM_uint32 _gss_acquire_cred_from(OM_uint32 *minor_status,
 		*minor_status = 0;
 		return GSS_S_UNAVAILABLE;
 	}
-	return __gss_acquire_cred_from(minor_status, desired_name, time_req, desired_mechs, cred_usage, cred_store, output_cred_handle, actual_mechs, time_rec);
+	return _GOGSSAPI_LOCKED(__gss_acquire_cred_from(minor_status, desired_name, time_req, desired_mechs, cred_usage, cred_store, output_cred_handle, actual_mechs, time_rec));
 }
 
 OM_uint32 (*__gss_store_cred_into)(OM_uint32 *minor_status,
@@ -70,7 +70,7 @@ OM_uint32 _gss_store_cred_into(OM_uint32 *minor_status,
 		*minor_status = 0;
 		return GSS_S_UNAVAILABLE;
 	}
-	return __gss_store_cred_into(minor_status, input_cred_handle, cred_usage, desired_mech, overwrite_cred, default_cred, cred_store, elements_stored, cred_usage_stored);
+	return _GOGSSAPI_LOCKED(__gss_store_cred_into(minor_status, input_cred_handle, cred_usage, desired_mech, overwrite_cred, default_cred, cred_store, elements_stored, cred_usage_stored));
 }
 
 OM_uint32 (*__gss_add_cred_from)(OM_uint32 *minor_status,
@@ -102,12 +102,13 @@ OM_uint32 _gss_add_cred_from (OM_uint32 *minor_status,
 		*minor_status = 0;
 		return GSS_S_UNAVAILABLE;
 	}
-	return __gss_add_cred_from(minor_status, input_cred_handle, desired_name, desired_mech, cred_usage, initiator_time_req, acceptor_time_req, cred_store, output_cred_handle, actual_mechs, initiator_time_rec, acceptor_time_rec);
+	return _GOGSSAPI_LOCKED(__gss_add_cred_from(minor_status, input_cred_handle, desired_name, desired_mech, cred_usage, initiator_time_req, acceptor_time_req, cred_store, output_cred_handle, actual_mechs, initiator_time_rec, acceptor_time_rec));
 }
 */
 import "C"
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"runtime"
@@ -127,22 +128,87 @@ func init() {
 	credStoreSymbols.Apply()
 }
 
-type credStore map[int]string
+// ErrUnsupportedCredStoreOption is returned when a credential store option is not known, or
+// cannot be used with the GSSAPI implementation
+var ErrUnsupportedCredStoreOption = errors.New("unsupported credential store option")
+
+// Credential store keys for each go-gssapi option.  MIT documents its keys in gss_store_cred_into(3).
+// Heimdal does not document its keys; TestCredStoreKeysAcquire acquires a credential with each of
+// the keys listed here.
+var (
+	mitCredStoreKeys = map[int]string{
+		int(g.CredStoreCCache):       "ccache",
+		int(g.CredStoreClientKeytab): "client_keytab",
+		int(g.CredStoreServerKeytab): "keytab",
+		int(g.CredStorePassword):     "password",
+		int(g.CredStoreRCache):       "rcache",
+		int(g.CredStoreVerify):       "verify",
+	}
+
+	heimdalCredStoreKeys = map[int]string{
+		int(g.CredStoreCCache):       "ccache",
+		int(g.CredStoreServerKeytab): "keytab",
+		int(g.CredStorePassword):     "password",
+	}
+)
+
+func credStoreKeys() map[int]string {
+	if isHeimdal() {
+		return heimdalCredStoreKeys
+	}
+	return mitCredStoreKeys
+}
+
+type credStoreKV struct {
+	key, value string
+}
+
+type credStore struct {
+	options map[int]string
+	raw     []credStoreKV // implementation specific keys, passed to the library as-is
+}
 
 func newCredStore() credStore {
-	return make(credStore)
+	return credStore{options: make(map[int]string)}
+}
+
+func errUnsupportedCredStoreOption(option int) error {
+	impl := "MIT"
+	if isHeimdal() {
+		impl = "Heimdal"
+	}
+	return fmt.Errorf("%w: option %d can not be used with %s Kerberos; use WithCredStoreRaw for implementation specific keys", ErrUnsupportedCredStoreOption, option, impl)
 }
 
 func (s *credStore) SetOption(option int, value string) error {
-	(*s)[option] = value
+	if _, ok := credStoreKeys()[option]; !ok {
+		return errUnsupportedCredStoreOption(option)
+	}
+
+	s.options[option] = value
 	return nil
 }
 
 func (s credStore) GetOption(option int) (string, bool) {
-	value, ok := s[option]
+	value, ok := s.options[option]
 	return value, ok
 }
 
+// WithCredStoreRaw passes a key and value to the credential store of the GSSAPI implementation
+// without any translation, for keys that have no go-gssapi option.  Examples are MIT's
+// client_principal key and Heimdal specific keys.  It can only be used with this provider.
+func WithCredStoreRaw(key, value string) g.CredStoreOption {
+	return func(s g.CredStore) error {
+		store, ok := s.(*credStore)
+		if !ok {
+			return fmt.Errorf("%w: raw option %q can not be used with %T", ErrUnsupportedCredStoreOption, key, s)
+		}
+
+		store.raw = append(store.raw, credStoreKV{key, value})
+		return nil
+	}
+}
+
 type kvset struct {
 	set    *C.struct_gss_key_value_set_struct
 	pinner *runtime.Pinner
@@ -155,7 +221,21 @@ func (k *kvset) constSet() C.gss_const_key_value_set_t {
 	return (C.gss_const_key_value_set_t)(unsafe.Pointer(k.set))
 }
 
-func (s credStore) kv() *kvset {
+// kv translates the options to the keys used by the GSSAPI implementation, returning an error for
+// options that the implementation does not support
+func (s credStore) kv() (*kvset, error) {
+	keys := credStoreKeys()
+
+	pairs := make([]credStoreKV, 0, len(s.options)+len(s.raw))
+	for opt, value := range s.options {
+		key, ok := keys[opt]
+		if !ok {
+			return nil, errUnsupportedCredStoreOption(opt)
+		}
+		pairs = append(pairs, credStoreKV{key, value})
+	}
+	pairs = append(pairs, s.raw...)
+
 	kvs := kvset{
 		set: &C.struct_gss_key_value_set_struct{
 			count:    0,
@@ -164,29 +244,12 @@ func (s credStore) kv() *kvset {
 		pinner: &runtime.Pinner{},
 	}
 
-	elms := make([]C.struct_gss_key_value_element_struct, 0, len(s))
-	for opt, value := range s {
-		elm := C.gss_key_value_element_desc{}
-
-		switch opt {
-		default:
-			continue
-		case int(g.CredStoreCCache):
-			elm.key = C.CString("ccache")
-		case int(g.CredStoreClientKeytab):
-			elm.key = C.CString("client_keytab")
-		case int(g.CredStoreServerKeytab):
-			elm.key = C.CString("keytab")
-		case int(g.CredStorePassword):
-			elm.key = C.CString("password")
-		case int(g.CredStoreRCache):
-			elm.key = C.CString("rcache")
-		case int(g.CredStoreVerify):
-			elm.key = C.CString("verify")
-		}
-
-		elm.value = C.CString(value)
-		elms = append(elms, elm)
+	elms := make([]C.struct_gss_key_value_element_struct, 0, len(pairs))
+	for _, pair := range pairs {
+		elms = append(elms, C.gss_key_value_element_desc{
+			key:   C.CString(pair.key),
+			value: C.CString(pair.value),
+		})
 	}
 
 	if len(elms) > 0 {
@@ -196,7 +259,7 @@ func (s credStore) kv() *kvset {
 		kvs.pinner.Pin(kvs.set.elements)
 	}
 
-	return &kvs
+	return &kvs, nil
 }
 
 func (k *kvset) Release() {
@@ -256,7 +319,10 @@ func (provider) AcquireCredentialFrom(name g.GssName, mechs []g.GssMech, usage g
 		}
 	}
 
-	kv := credStore.kv()
+	kv, err := credStore.kv()
+	if err != nil {
+		return nil, err
+	}
 	defer kv.Release()
 
 	var cMinor C.OM_uint32
@@ -276,6 +342,16 @@ func (provider) AcquireCredentialFrom(name g.GssName, mechs []g.GssMech, usage g
 	return cred, nil
 }
 
+// AcquireCredentialFromContext is like AcquireCredentialFrom but returns ctx.Err() if ctx is cancelled
+// or its deadline passes before the library returns, for example while a password is being used to
+// obtain a TGT from the KDC.  The abandoned call is left to finish in the background and any
+// credential it acquires is released.
+func (p provider) AcquireCredentialFromContext(ctx context.Context, name g.GssName, mechs []g.GssMech, usage g.CredUsage, lifetime *g.GssLifetime, opts ...g.CredStoreOption) (g.Credential, error) {
+	return acquireCancelable(ctx, name, func(name g.GssName) (g.Credential, error) {
+		return p.AcquireCredentialFrom(name, mechs, usage, lifetime, opts...)
+	})
+}
+
 func (c *Credential) StoreInto(mech g.GssMech, usage g.CredUsage, overwrite bool, defaultCred bool, opts ...g.CredStoreOption) (mechsStored []g.GssMech, usageStored g.CredUsage, err error) {
 	mechOid := g.Oid{}
 	if mech != nil {
@@ -289,7 +365,10 @@ func (c *Credential) StoreInto(mech g.GssMech, usage g.CredUsage, overwrite bool
 		}
 	}
 
-	kv := credStore.kv()
+	kv, err := credStore.kv()
+	if err != nil {
+		return nil, 0, err
+	}
 	defer kv.Release()
 
 	var cMinor C.OM_uint32
@@ -317,16 +396,11 @@ func (c *Credential) StoreInto(mech g.GssMech, usage g.CredUsage, overwrite bool
 	mechs := make([]g.GssMech, 0, cElementsStored.count)
 	mechOids := oidsFromGssOidSet(cElementsStored)
 	for _, oid := range mechOids {
-		mech, err := g.MechFromOid(oid)
-		switch {
-		default:
-			mechs = append(mechs, mech)
-		case errors.Is(err, g.ErrBadMech):
-			// warn
-			continue
-		case err != nil:
+		mech, err := mechFromOid(oid)
+		if err != nil {
 			return nil, 0, err
 		}
+		mechs = append(mechs, mech)
 	}
 
 	return mechs, g.CredUsage(cUsageStored), nil
@@ -364,7 +438,10 @@ func (c *Credential) AddFrom(name g.GssName, mech g.GssMech, usage g.CredUsage,
 		}
 	}
 
-	kv := credStore.kv()
+	kv, err := credStore.kv()
+	if err != nil {
+		return nil, err
+	}
 	defer kv.Release()
 
 	var minor C.OM_uint32
@@ -386,6 +463,7 @@ func (c *Credential) AddFrom(name g.GssName, mech g.GssMech, usage g.CredUsage,
 			id:           cCredOut,
 			usage:        usage,
 			isFromNoName: cGssName == C.GSS_C_NO_NAME,
+			ownedCCache:  c.ownedCCache.ref(),
 		}, nil
 	}
 }
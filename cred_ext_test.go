@@ -3,8 +3,11 @@
 package gssapi
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
 
+	"github.com/golang-auth/go-gssapi-c/gsstest"
 	g "github.com/golang-auth/go-gssapi/v3"
 )
 
@@ -13,7 +16,7 @@ func TestNewCredStore(t *testing.T) {
 
 	store := newCredStore()
 	assert.NotNil(store)
-	assert.Equal(0, len(store))
+	assert.Equal(0, len(store.options))
 }
 
 func TestCredStoreSetOption(t *testing.T) {
@@ -24,21 +27,21 @@ func TestCredStoreSetOption(t *testing.T) {
 	// Test setting a single option
 	err := store.SetOption(int(g.CredStoreCCache), "FILE:/tmp/test")
 	assert.NoError(err)
-	assert.Equal(1, len(store))
+	assert.Equal(1, len(store.options))
 
 	// Test setting multiple options
-	err = store.SetOption(int(g.CredStoreClientKeytab), "FILE:/tmp/keytab")
+	err = store.SetOption(int(g.CredStoreServerKeytab), "FILE:/tmp/keytab")
 	assert.NoError(err)
-	assert.Equal(2, len(store))
+	assert.Equal(2, len(store.options))
 
 	err = store.SetOption(int(g.CredStorePassword), "secret")
 	assert.NoError(err)
-	assert.Equal(3, len(store))
+	assert.Equal(3, len(store.options))
 
 	// Test overwriting an existing option
 	err = store.SetOption(int(g.CredStoreCCache), "FILE:/tmp/other")
 	assert.NoError(err)
-	assert.Equal(3, len(store))
+	assert.Equal(3, len(store.options))
 }
 
 func TestCredStoreGetOption(t *testing.T) {
@@ -83,17 +86,26 @@ func TestCredStoreGetSetAllOptions(t *testing.T) {
 		{int(g.CredStoreVerify), "host/test.example.com"},
 	}
 
-	// Set all options
+	// Set all options that the implementation supports
+	keys := credStoreKeys()
 	for _, tt := range tests {
 		err := store.SetOption(tt.option, tt.value)
+		if _, ok := keys[tt.option]; !ok {
+			assert.ErrorIs(err, ErrUnsupportedCredStoreOption)
+			continue
+		}
 		assert.NoError(err, "Failed to set option %d", tt.option)
 	}
 
-	assert.Equal(len(tests), len(store))
+	assert.Equal(len(keys), len(store.options))
 
 	// Retrieve all options
 	for _, tt := range tests {
 		value, ok := store.GetOption(tt.option)
+		if _, supported := keys[tt.option]; !supported {
+			assert.False(ok, "Option %d should not exist", tt.option)
+			continue
+		}
 		assert.True(ok, "Option %d should exist", tt.option)
 		assert.Equal(tt.value, value, "Option %d should have correct value", tt.option)
 	}
@@ -104,7 +116,8 @@ func TestCredStoreKvEmpty(t *testing.T) {
 
 	store := newCredStore()
 
-	kv := store.kv()
+	kv, err := store.kv()
+	assert.NoErrorFatal(err)
 	assert.NotNil(kv.set)
 	assert.Equal(0, int(kv.set.count))
 
@@ -118,7 +131,8 @@ func TestCredStoreKvSingleOption(t *testing.T) {
 	err := store.SetOption(int(g.CredStoreCCache), "FILE:/tmp/test")
 	assert.NoError(err)
 
-	kv := store.kv()
+	kv, err := store.kv()
+	assert.NoErrorFatal(err)
 	defer kv.Release()
 
 	assert.NotNil(kv.set)
@@ -147,12 +161,18 @@ func TestCredStoreKvOptionKeyMappings(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := credStoreKeys()[tt.option]; !ok {
+				t.Log("skipping key mapping test because the option is not supported by the GSSAPI implementation")
+				t.SkipNow()
+			}
+
 			assert := NewAssert(t)
 			store := newCredStore()
 			err := store.SetOption(tt.option, tt.value)
 			assert.NoError(err)
 
-			kvset := store.kv()
+			kvset, err := store.kv()
+			assert.NoErrorFatal(err)
 			defer kvset.Release()
 
 			assert.NotNil(kvset.set)
@@ -183,17 +203,24 @@ func TestCredStoreKvAllOptions(t *testing.T) {
 		int(g.CredStoreVerify):       "host/test.example.com",
 	}
 
-	// Set all options
+	// Set all options that the implementation supports
+	keys := credStoreKeys()
 	for opt, value := range testValues {
 		err := store.SetOption(opt, value)
+		if _, ok := keys[opt]; !ok {
+			// Heimdal has no key for the client keytab, for example
+			assert.ErrorIs(err, ErrUnsupportedCredStoreOption)
+			continue
+		}
 		assert.NoError(err)
 	}
 
-	kvset := store.kv()
+	kvset, err := store.kv()
+	assert.NoErrorFatal(err)
 	defer kvset.Release()
 
 	assert.NotNil(kvset.set)
-	assert.Equal(len(testValues), int(kvset.set.count))
+	assert.Equal(len(keys), int(kvset.set.count))
 	assert.NotNil(kvset.set.elements)
 }
 
@@ -205,7 +232,8 @@ func TestCredStoreKv(t *testing.T) {
 	assert.NoError(err)
 
 	// Pass nil pinner - should create a new one
-	kvset := store.kv()
+	kvset, err := store.kv()
+	assert.NoErrorFatal(err)
 	defer kvset.Release()
 
 	assert.NotNil(kvset.set)
@@ -216,11 +244,12 @@ func TestCredStoreKvUnknownOption(t *testing.T) {
 	assert := NewAssert(t)
 
 	store := newCredStore()
-	// Set an option that's not in the switch statement
 	err := store.SetOption(999, "unknown value")
-	assert.NoError(err)
+	assert.ErrorIs(err, ErrUnsupportedCredStoreOption)
+	assert.Equal(0, len(store.options))
 
-	kvset := store.kv()
+	kvset, err := store.kv()
+	assert.NoErrorFatal(err)
 	defer kvset.Release()
 
 	assert.NotNil(kvset.set)
@@ -235,6 +264,142 @@ func TestCredStoreKvUnknownOption(t *testing.T) {
 	assert.Equal("", value)
 }
 
+func TestCredStoreSetOptionUnsupportedByImplementation(t *testing.T) {
+	assert := NewAssert(t)
+
+	store := newCredStore()
+	err := store.SetOption(int(g.CredStoreClientKeytab), "FILE:/tmp/client.keytab")
+	if isHeimdal() {
+		assert.ErrorIs(err, ErrUnsupportedCredStoreOption)
+		assert.Contains(err.Error(), "Heimdal")
+		assert.Equal(0, len(store.options))
+		return
+	}
+	assert.NoError(err)
+
+	kvset, err := store.kv()
+	assert.NoErrorFatal(err)
+	kvset.Release()
+}
+
+// The keys that options are translated to must be understood by the implementation, so acquire a
+// credential from a KDC with each of them
+func TestCredStoreKeysAcquire(t *testing.T) {
+	if !ta.lib.HasExtension(g.HasExtCredStore) {
+		t.Log("skipping credential store test because the CredStore extension is not available")
+		t.SkipNow()
+	}
+
+	assert := NewAssert(t)
+
+	kdc := gsstest.StartT(t)
+	t.Setenv("KRB5_CONFIG", kdc.Krb5Conf())
+	assert.NoErrorFatal(kdc.AddPrincipal("robot", "password"))
+	assert.NoErrorFatal(kdc.AddRandomKeyPrincipal("keytab-robot"))
+	assert.NoErrorFatal(kdc.AddRandomKeyPrincipal("host/store.golang-auth.io"))
+	ccache, err := kdc.CCache("robot", "password")
+	assert.NoErrorFatal(err)
+	clientKeytab, err := kdc.Keytab("keytab-robot")
+	assert.NoErrorFatal(err)
+	keytab, err := kdc.Keytab("host/store.golang-auth.io")
+	assert.NoErrorFatal(err)
+
+	robot, err := ta.lib.ImportName(kdc.Principal("robot"), g.GSS_KRB5_NT_PRINCIPAL_NAME)
+	assert.NoErrorFatal(err)
+	defer releaseName(robot)
+	keytabRobot, err := ta.lib.ImportName(kdc.Principal("keytab-robot"), g.GSS_KRB5_NT_PRINCIPAL_NAME)
+	assert.NoErrorFatal(err)
+	defer releaseName(keytabRobot)
+
+	tests := []struct {
+		key    string
+		option g.CredStoreOpt
+		name   g.GssName
+		usage  g.CredUsage
+		opts   []g.CredStoreOption
+	}{
+		{"ccache", g.CredStoreCCache, nil, g.CredUsageInitiateOnly, []g.CredStoreOption{
+			g.WithCredStoreCCache("FILE:" + ccache),
+		}},
+		{"keytab", g.CredStoreServerKeytab, nil, g.CredUsageAcceptOnly, []g.CredStoreOption{
+			g.WithCredStoreServerKeytab("FILE:" + keytab),
+		}},
+		{"password", g.CredStorePassword, robot, g.CredUsageInitiateOnly, []g.CredStoreOption{
+			g.WithCredStorePassword("password"),
+		}},
+		{"client_keytab", g.CredStoreClientKeytab, keytabRobot, g.CredUsageInitiateOnly, []g.CredStoreOption{
+			g.WithCredStoreClientKeytab("FILE:" + clientKeytab),
+			g.WithCredStoreCCache("FILE:" + filepath.Join(t.TempDir(), "ccache")),
+		}},
+	}
+
+	p := ta.lib.(g.ProviderExtCredStore)
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if key, ok := credStoreKeys()[int(tt.option)]; !ok {
+				t.Log("skipping credential store test because the option is not supported by the GSSAPI implementation")
+				t.SkipNow()
+			} else {
+				assert.Equal(tt.key, key)
+			}
+
+			assert := NewAssert(t)
+
+			cred, err := p.AcquireCredentialFrom(tt.name, []g.GssMech{g.GSS_MECH_KRB5}, tt.usage, nil, tt.opts...)
+			assert.NoErrorFatal(err)
+			defer cred.Release() //nolint:errcheck
+
+			info, err := cred.Inquire()
+			assert.NoErrorFatal(err)
+			assert.Equal(tt.usage, info.Usage)
+		})
+	}
+}
+
+func TestCredStoreRaw(t *testing.T) {
+	assert := NewAssert(t)
+
+	store := newCredStore()
+	err := g.WithCredStoreCCache("FILE:/tmp/ccache")(&store)
+	assert.NoError(err)
+	err = WithCredStoreRaw("client_principal", "robot@GOLANG-AUTH.IO")(&store)
+	assert.NoError(err)
+
+	// raw keys are not go-gssapi options
+	assert.Equal(1, len(store.options))
+
+	kvset, err := store.kv()
+	assert.NoErrorFatal(err)
+	defer kvset.Release()
+
+	assert.Equal(2, int(kvset.set.count))
+	key, value := kvset.kv(0)
+	assert.Equal("ccache", key)
+	assert.Equal("FILE:/tmp/ccache", value)
+	key, value = kvset.kv(1)
+	assert.Equal("client_principal", key)
+	assert.Equal("robot@GOLANG-AUTH.IO", value)
+}
+
+type otherCredStore map[int]string
+
+func (s otherCredStore) SetOption(option int, value string) error {
+	s[option] = value
+	return nil
+}
+
+func (s otherCredStore) GetOption(option int) (string, bool) {
+	value, ok := s[option]
+	return value, ok
+}
+
+func TestCredStoreRawOtherStore(t *testing.T) {
+	assert := NewAssert(t)
+
+	err := WithCredStoreRaw("ccache", "FILE:/tmp/ccache")(otherCredStore{})
+	assert.ErrorIs(err, ErrUnsupportedCredStoreOption)
+}
+
 func TestCredStoreSetOptionEmptyValue(t *testing.T) {
 	assert := NewAssert(t)
 
@@ -261,7 +426,7 @@ func TestCredStoreMultipleOptionsSameValue(t *testing.T) {
 	err = store.SetOption(int(g.CredStoreServerKeytab), value)
 	assert.NoError(err)
 
-	assert.Equal(2, len(store))
+	assert.Equal(2, len(store.options))
 
 	// Both should return the same value
 	ccacheValue, ok := store.GetOption(int(g.CredStoreCCache))
@@ -357,6 +522,12 @@ func TestStoreInfo(t *testing.T) {
 
 	// Verify the credential was stored
 	assert.FileExists(tmpStore)
+
+	cc, err := ReadCCache("FILE:" + tmpStore)
+	assert.NoErrorFatal(err)
+	assert.Equal("robot@GOLANG-AUTH.IO", cc.DefaultPrincipal.String())
+	assert.NotEmpty(cc.Credentials)
+	assert.Equal("krbtgt/GOLANG-AUTH.IO@GOLANG-AUTH.IO", cc.Credentials[0].Server.String())
 }
 
 func TestAddCredentialFrom(t *testing.T) {
@@ -367,3 +538,34 @@ func TestAddCredentialFrom(t *testing.T) {
 
 	// TODO: it's not clear how to test this
 }
+
+func TestAcquireCredentialFromContext(t *testing.T) {
+	if !ta.lib.HasExtension(g.HasExtCredStore) {
+		t.Log("skipping acquire credential from test because provider does not support the CredStore extension")
+		t.SkipNow()
+	}
+
+	assert := NewAssert(t)
+
+	mechs := []g.GssMech{g.GSS_MECH_KRB5}
+	p := ta.lib.(*provider)
+
+	opts := []g.CredStoreOption{
+		g.WithCredStoreCCache("FILE:/no/such/file"),
+		g.WithCredStoreServerKeytab("FILE:" + ta.ktfileRack),
+	}
+
+	cred, err := p.AcquireCredentialFromContext(context.Background(), nil, mechs, g.CredUsageAcceptOnly, nil, opts...)
+	assert.NoError(err)
+	if cred != nil {
+		_ = cred.Release()
+	}
+
+	_, err = p.AcquireCredentialFromContext(context.Background(), nil, mechs, g.CredUsageInitiateOnly, nil, opts...)
+	assert.Error(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = p.AcquireCredentialFromContext(ctx, nil, mechs, g.CredUsageAcceptOnly, nil, opts...)
+	assert.ErrorIs(err, context.Canceled)
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+/*
+#include "gss.h"
+#include <stdint.h>
+
+// The function pointers are set to the library functions by the init function using symbolMap.Apply()
+OM_uint32 (*__gogssapi_krb5_set_allowable_enctypes)(OM_uint32 *minor_status,
+	gss_cred_id_t cred,
+	OM_uint32 num_ktypes,
+	int32_t *ktypes) = NULL;
+
+OM_uint32 (*__gogssapi_set_cred_option)(OM_uint32 *minor_status,
+	gss_cred_id_t *cred_handle,
+	const gss_OID desired_object,
+	const gss_buffer_t value) = NULL;
+
+OM_uint32 _gogssapi_krb5_set_allowable_enctypes(OM_uint32 *minor_status, gss_cred_id_t cred, OM_uint32 num_ktypes, int32_t *ktypes) {
+	if( __gogssapi_krb5_set_allowable_enctypes == NULL ) {
+		*minor_status = 0;
+		return GSS_S_UNAVAILABLE;
+	}
+	return _GOGSSAPI_LOCKED(__gogssapi_krb5_set_allowable_enctypes(minor_status, cred, num_ktypes, ktypes));
+}
+
+OM_uint32 _gogssapi_set_cred_option(OM_uint32 *minor_status, gss_cred_id_t *cred_handle, const gss_OID desired_object, const gss_buffer_t value) {
+	if( __gogssapi_set_cred_option == NULL ) {
+		*minor_status = 0;
+		return GSS_S_UNAVAILABLE;
+	}
+	return _GOGSSAPI_LOCKED(__gogssapi_set_cred_option(minor_status, cred_handle, desired_object, value));
+}
+*/
+import "C"
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"runtime"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// Map optional symbols from the GSSAPI library to the wrapper function pointers
+var credOptionSymbols = symbolMap{
+	"gss_krb5_set_allowable_enctypes": &C.__gogssapi_krb5_set_allowable_enctypes,
+	"gss_set_cred_option":             &C.__gogssapi_set_cred_option,
+}
+
+func init() {
+	credOptionSymbols.Apply()
+}
+
+// Kerberos options for Credential.SetOption
+var (
+	// Don't assert the confidentiality and integrity flags in the authenticator of an initiator
+	// credential (MIT and Heimdal).  Some legacy peers, such as Windows LDAP servers, then
+	// require every message to be sealed or signed.  The value is empty.
+	GSS_KRB5_CRED_NO_CI_FLAGS_X = mustString2Oid("1.2.752.43.13.29")
+	// The Heimdal option used by SetAllowableEnctypes; the value is a list of 32 bit big
+	// endian encryption types
+	GSS_KRB5_SET_ALLOWABLE_ENCTYPES_X = mustString2Oid("1.2.752.43.13.14")
+)
+
+// SetOption sets an option of the credential with gss_set_cred_option.  The options are
+// identified by OID and are specific to the implementation; see the GSS_KRB5_CRED_* OIDs
+// for example.  GSS_S_UNAVAILABLE is returned if the library does not support the function
+// and usually if it does not understand the option.
+func (c *Credential) SetOption(option g.Oid, value []byte) error {
+	if c.id == nil {
+		return g.ErrNoCred
+	}
+	if len(value) > math.MaxUint32 {
+		return ErrTooLarge
+	}
+
+	pinner := &runtime.Pinner{}
+	defer pinner.Unpin()
+	cOption, _ := oid2Coid(option, pinner)
+	cValue, _ := bytesToCBuffer(value, pinner)
+
+	var minor C.OM_uint32
+	major := C._gogssapi_set_cred_option(&minor, &c.id, cOption, &cValue)
+	// the options that MIT and Heimdal understand are all for the Kerberos mechanism
+	return makeMechStatus(major, minor, g.GSS_MECH_KRB5)
+}
+
+// SetAllowableEnctypes restricts the Kerberos encryption types that the credential will use for
+// tickets and session keys to those in enctypes, overriding the permitted_enctypes and
+// default_tkt_enctypes settings of krb5.conf.  It uses gss_krb5_set_allowable_enctypes, or its
+// Heimdal credential option.
+func (c *Credential) SetAllowableEnctypes(enctypes []int32) error {
+	if c.id == nil {
+		return g.ErrNoCred
+	}
+	if len(enctypes) == 0 {
+		return fmt.Errorf("no encryption types: %w", g.ErrFailure)
+	}
+	if len(enctypes) > math.MaxUint32/4 {
+		return ErrTooLarge
+	}
+
+	if !hasSymbol("gss_krb5_set_allowable_enctypes") && isHeimdal() {
+		value := make([]byte, 0, 4*len(enctypes))
+		for _, etype := range enctypes {
+			value = binary.BigEndian.AppendUint32(value, uint32(etype))
+		}
+		return c.SetOption(GSS_KRB5_SET_ALLOWABLE_ENCTYPES_X, value)
+	}
+
+	pinner := &runtime.Pinner{}
+	defer pinner.Unpin()
+	pinner.Pin(&enctypes[0])
+
+	var minor C.OM_uint32
+	major := C._gogssapi_krb5_set_allowable_enctypes(&minor, c.id, C.OM_uint32(len(enctypes)), (*C.int32_t)(&enctypes[0]))
+	return makeMechStatus(major, minor, g.GSS_MECH_KRB5)
+}
+
+// SetNoCIFlags stops an initiator credential asserting the confidentiality and integrity flags
+// in the authenticator checksum, for peers that insist on protecting every message if they are
+// present.  It sets the GSS_KRB5_CRED_NO_CI_FLAGS_X option.
+func (c *Credential) SetNoCIFlags() error {
+	return c.SetOption(GSS_KRB5_CRED_NO_CI_FLAGS_X, nil)
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"testing"
+
+	"github.com/golang-auth/go-gssapi-c/gsstest"
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+func hasAllowableEnctypes() bool {
+	return hasSymbol("gss_krb5_set_allowable_enctypes") || (isHeimdal() && hasSymbol("gss_set_cred_option"))
+}
+
+func TestSetAllowableEnctypes(t *testing.T) {
+	assert := NewAssert(t)
+	if !hasAllowableEnctypes() {
+		t.Skip("allowable enctypes cannot be set with this GSSAPI library")
+	}
+	if !hasSymbol("gss_inquire_sec_context_by_oid") {
+		t.Skip("the session key cannot be inquired with this GSSAPI library")
+	}
+
+	// a KDC is needed to issue a ticket with the restricted enctype; it would otherwise choose
+	// AES256, as in the ticket of the test credentials cache
+	kdc := gsstest.StartT(t)
+	assert.NoErrorFatal(kdc.AddPrincipal("robot", "password"))
+	assert.NoErrorFatal(kdc.AddRandomKeyPrincipal("rack/foo.golang-auth.io"))
+	ccache, err := kdc.CCache("robot", "password")
+	assert.NoErrorFatal(err)
+	keytab, err := kdc.Keytab("rack/foo.golang-auth.io")
+	assert.NoErrorFatal(err)
+
+	t.Setenv("KRB5_CONFIG", kdc.Krb5Conf())
+	t.Setenv("KRB5CCNAME", "FILE:"+ccache)
+	t.Setenv("KRB5_KTNAME", "FILE:"+keytab)
+
+	cred, err := ta.lib.AcquireCredential(nil, []g.GssMech{g.GSS_MECH_KRB5}, g.CredUsageInitiateOnly, nil)
+	assert.NoErrorFatal(err)
+	defer cred.Release() //nolint:errcheck
+
+	lCred := cred.(*Credential)
+	assert.ErrorIs(lCred.SetAllowableEnctypes(nil), g.ErrFailure)
+	assert.NoErrorFatal(lCred.SetAllowableEnctypes([]int32{EnctypeAes128CtsHmacSha196}))
+
+	_, acceptor, _ := establishContextsWith(t, "rack@foo.golang-auth.io", contextOptions{
+		initiator: []g.InitSecContextOption{
+			g.WithInitiatorFlags(g.ContextFlagMutual),
+			g.WithInitiatorCredential(cred),
+		},
+	})
+
+	etype, err := acceptor.SessionKeyEnctype()
+	assert.NoErrorFatal(err)
+	assert.Equal(int32(EnctypeAes128CtsHmacSha196), etype)
+}
+
+func TestSetAllowableEnctypesRefusesTicket(t *testing.T) {
+	assert := NewAssert(t)
+	// Heimdal only applies the restriction to new tickets
+	if !hasSymbol("gss_krb5_set_allowable_enctypes") || isHeimdal() {
+		t.Skip("gss_krb5_set_allowable_enctypes is not available in this GSSAPI library")
+	}
+	ta.useAsset(t, testCredCache|testKeytabRack)
+
+	cred, err := ta.lib.AcquireCredential(nil, []g.GssMech{g.GSS_MECH_KRB5}, g.CredUsageInitiateOnly, nil)
+	assert.NoErrorFatal(err)
+	defer cred.Release() //nolint:errcheck
+
+	err = cred.(*Credential).SetAllowableEnctypes([]int32{EnctypeArcfourHmac})
+	assert.NoErrorFatal(err)
+
+	name, err := ta.lib.ImportName("rack@foo.golang-auth.io", g.GSS_NT_HOSTBASED_SERVICE)
+	assert.NoErrorFatal(err)
+	defer name.Release() //nolint:errcheck
+
+	// the cached AES service ticket can't be used and there is no KDC to get another
+	secCtx, err := ta.lib.InitSecContext(name, g.WithInitiatorCredential(cred))
+	if err == nil {
+		defer secCtx.Delete() //nolint:errcheck
+		_, _, err = secCtx.Continue(nil)
+	}
+	assert.Error(err)
+}
+
+func TestSetNoCIFlags(t *testing.T) {
+	assert := NewAssert(t)
+	if !hasSymbol("gss_set_cred_option") {
+		t.Skip("gss_set_cred_option is not available in this GSSAPI library")
+	}
+	ta.useAsset(t, testCredCache|testKeytabRack)
+
+	cred, err := ta.lib.AcquireCredential(nil, []g.GssMech{g.GSS_MECH_KRB5}, g.CredUsageInitiateOnly, nil)
+	assert.NoErrorFatal(err)
+	defer cred.Release() //nolint:errcheck
+
+	// the flags are asserted without the option, even though they were not requested
+	_, acceptor := establishContexts(t, g.ContextFlagMutual, g.WithInitiatorCredential(cred))
+	info, err := acceptor.Inquire()
+	assert.NoErrorFatal(err)
+	assert.Equal(g.ContextFlagConf|g.ContextFlagInteg, info.Flags&(g.ContextFlagConf|g.ContextFlagInteg))
+
+	lCred := cred.(*Credential)
+	assert.NoErrorFatal(lCred.SetNoCIFlags())
+
+	initiator, acceptor := establishContexts(t, g.ContextFlagMutual, g.WithInitiatorCredential(cred))
+	info, err = acceptor.Inquire()
+	assert.NoErrorFatal(err)
+	assert.Zero(info.Flags & (g.ContextFlagConf | g.ContextFlagInteg))
+
+	// messages can still be protected
+	msg := []byte("Hello GSSAPI")
+	mic, err := initiator.GetMIC(msg, 0)
+	assert.NoError(err)
+	_, err = acceptor.VerifyMIC(msg, mic)
+	assert.NoError(err)
+
+	// an option that no library knows about
+	assert.Error(lCred.SetOption(mustString2Oid("1.3.6.1.4.1.57264.999.1"), nil))
+
+	// a released credential
+	released := &Credential{}
+	assert.ErrorIs(released.SetOption(GSS_KRB5_CRED_NO_CI_FLAGS_X, nil), g.ErrNoCred)
+	assert.ErrorIs(released.SetNoCIFlags(), g.ErrNoCred)
+	assert.ErrorIs(released.SetAllowableEnctypes([]int32{EnctypeAes128CtsHmacSha196}), g.ErrNoCred)
+}
@@ -3,6 +3,7 @@
 package gssapi
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -362,5 +363,41 @@ func TestAddCredentialWithName(t *testing.T) {
 
 func TestHasDuplicateCred(t *testing.T) {
 	assert := NewAssert(t)
-	assert.Equal(hasDuplicateCred(), optionalSymbols["gss_duplicate_cred"] != nil)
+	assert.Equal(hasDuplicateCred(), hasSymbol("gss_duplicate_cred"))
+}
+
+func TestAcquireCredentialContext(t *testing.T) {
+	assert := NewAssert(t)
+
+	ta.useAsset(t, testCredCache|testKeytabRack|testCfg1)
+
+	mechs := []g.GssMech{g.GSS_MECH_KRB5}
+	p := ta.lib.(*provider)
+
+	nameInitiator, err := ta.lib.ImportName(cliname, g.GSS_NT_USER_NAME)
+	assert.NoErrorFatal(err)
+	defer releaseName(nameInitiator)
+
+	cred, err := p.AcquireCredentialContext(context.Background(), nameInitiator, mechs, g.CredUsageInitiateOnly, nil)
+	assert.NoErrorFatal(err)
+	defer cred.Release() //nolint:errcheck
+
+	// the caller's name must still be usable
+	_, _, err = nameInitiator.Display()
+	assert.NoError(err)
+
+	info, err := cred.Inquire()
+	assert.NoErrorFatal(err)
+	assert.Equal(cliname, info.Name)
+
+	_, err = p.AcquireCredentialContext(context.Background(), nameInitiator, mechs, g.CredUsageAcceptOnly, nil)
+	assert.Error(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = p.AcquireCredentialContext(ctx, nameInitiator, mechs, g.CredUsageInitiateOnly, nil)
+	assert.ErrorIs(err, context.Canceled)
+
+	_, err = p.AcquireCredentialContext(context.Background(), &someName{}, mechs, g.CredUsageInitiateOnly, nil)
+	assert.ErrorIs(err, g.ErrBadName)
 }
@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The token decoders need a little more than encoding/asn1 offers: Kerberos uses GeneralString,
+// which encoding/asn1 refuses to decode, and its messages are built from explicitly tagged
+// SEQUENCE members that are easier to pick apart one TLV at a time.
+
+// ErrMalformedToken is returned when a token cannot be decoded
+var ErrMalformedToken = errors.New("malformed token")
+
+// tag classes
+const (
+	derClassUniversal   = 0
+	derClassApplication = 1
+	derClassContext     = 2
+)
+
+// universal tags used by the decoders
+const (
+	derTagOctetString = 4
+	derTagOID         = 6
+	derTagSequence    = 16
+)
+
+// derValue is a single BER/DER TLV
+type derValue struct {
+	class       int
+	constructed bool
+	tag         int
+	body        []byte // the contents octets
+	full        []byte // the complete TLV including the header
+}
+
+// readDER reads one TLV from the front of b, returning the rest of the input.  Only definite
+// lengths are accepted.
+func readDER(b []byte) (v derValue, rest []byte, err error) {
+	if len(b) < 2 {
+		return v, nil, fmt.Errorf("%w: truncated DER header", ErrMalformedToken)
+	}
+
+	v.class = int(b[0] >> 6)
+	v.constructed = b[0]&0x20 != 0
+	v.tag = int(b[0] & 0x1f)
+	offset := 1
+
+	// high tag number form
+	if v.tag == 0x1f {
+		v.tag = 0
+		for {
+			if offset >= len(b) || offset > 4 {
+				return v, nil, fmt.Errorf("%w: bad DER tag", ErrMalformedToken)
+			}
+			c := b[offset]
+			offset++
+			v.tag = v.tag<<7 | int(c&0x7f)
+			if c&0x80 == 0 {
+				break
+			}
+		}
+	}
+
+	length, n, err := readDERLength(b[offset:])
+	if err != nil {
+		return v, nil, err
+	}
+	offset += n
+
+	if length > len(b)-offset {
+		return v, nil, fmt.Errorf("%w: DER length %d exceeds available data (%d bytes)", ErrMalformedToken, length, len(b)-offset)
+	}
+
+	v.body = b[offset : offset+length]
+	v.full = b[:offset+length]
+
+	return v, b[offset+length:], nil
+}
+
+// readDERLength decodes a DER length, returning the length and the number of octets
+// it occupied.
+func readDERLength(b []byte) (length int, n int, err error) {
+	if len(b) < 1 {
+		return 0, 0, fmt.Errorf("%w: truncated DER length", ErrMalformedToken)
+	}
+
+	if b[0]&0x80 == 0 {
+		return int(b[0]), 1, nil
+	}
+
+	numOctets := int(b[0] & 0x7f)
+	switch {
+	case numOctets == 0:
+		return 0, 0, fmt.Errorf("%w: indefinite DER length", ErrMalformedToken)
+	case numOctets > 4:
+		return 0, 0, fmt.Errorf("%w: DER length too large", ErrMalformedToken)
+	case numOctets >= len(b):
+		return 0, 0, fmt.Errorf("%w: truncated DER length", ErrMalformedToken)
+	}
+
+	for i := 1; i <= numOctets; i++ {
+		length = length<<8 | int(b[i])
+	}
+	if length < 0 || length > 0x7fffffff {
+		return 0, 0, fmt.Errorf("%w: DER length too large", ErrMalformedToken)
+	}
+
+	return length, numOctets + 1, nil
+}
+
+// expectDER reads one TLV and checks its class and tag
+func expectDER(b []byte, class, tag int) (v derValue, rest []byte, err error) {
+	v, rest, err = readDER(b)
+	if err != nil {
+		return v, nil, err
+	}
+	if v.class != class || v.tag != tag {
+		return v, nil, fmt.Errorf("%w: unexpected DER element (class %d tag %d), wanted class %d tag %d", ErrMalformedToken, v.class, v.tag, class, tag)
+	}
+
+	return v, rest, nil
+}
+
+// derFields splits the body of a SEQUENCE of explicitly context-tagged members into a
+// map of tag number to the TLV inside each tag.
+func derFields(seq []byte) (map[int][]byte, error) {
+	fields := make(map[int][]byte)
+	for len(seq) > 0 {
+		v, rest, err := readDER(seq)
+		if err != nil {
+			return nil, err
+		}
+		if v.class != derClassContext {
+			return nil, fmt.Errorf("%w: expected a context tagged member", ErrMalformedToken)
+		}
+		fields[v.tag] = v.body
+		seq = rest
+	}
+
+	return fields, nil
+}
+
+func derInt32(b []byte) (int32, error) {
+	var i int32
+	rest, err := asn1.Unmarshal(b, &i)
+	if err == nil && len(rest) > 0 {
+		err = errors.New("trailing data")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad INTEGER: %w", ErrMalformedToken, err)
+	}
+
+	return i, nil
+}
+
+func derTime(b []byte) (time.Time, error) {
+	var t time.Time
+	_, err := asn1.UnmarshalWithParams(b, &t, "generalized")
+	if err != nil {
+		return t, fmt.Errorf("%w: bad KerberosTime: %w", ErrMalformedToken, err)
+	}
+
+	return t, nil
+}
+
+func derOctetString(b []byte) ([]byte, error) {
+	v, _, err := expectDER(b, derClassUniversal, derTagOctetString)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.body, nil
+}
+
+// derKerberosString decodes a KerberosString (GeneralString restricted to IA5 by RFC 4120).
+// Implementations put UTF-8 in there in practice, so it is returned as-is.
+func derKerberosString(b []byte) (string, error) {
+	v, _, err := readDER(b)
+	if err != nil {
+		return "", err
+	}
+	if v.class != derClassUniversal || v.constructed {
+		return "", fmt.Errorf("%w: bad KerberosString", ErrMalformedToken)
+	}
+
+	return string(v.body), nil
+}
+
+// derSequence returns the body of a SEQUENCE
+func derSequence(b []byte) ([]byte, error) {
+	v, _, err := expectDER(b, derClassUniversal, derTagSequence)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.body, nil
+}
@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import "strconv"
+
+// Kerberos encryption type numbers (RFC 3961 § 8 and the IANA Kerberos parameters registry)
+const (
+	EnctypeDesCbcCrc              = 1
+	EnctypeDesCbcMd4              = 2
+	EnctypeDesCbcMd5              = 3
+	EnctypeDes3CbcSha1            = 16
+	EnctypeAes128CtsHmacSha196    = 17
+	EnctypeAes256CtsHmacSha196    = 18
+	EnctypeAes128CtsHmacSha256128 = 19
+	EnctypeAes256CtsHmacSha384192 = 20
+	EnctypeArcfourHmac            = 23
+	EnctypeArcfourHmacExp         = 24
+	EnctypeCamellia128CtsCmac     = 25
+	EnctypeCamellia256CtsCmac     = 26
+)
+
+var enctypeNames = map[int32]string{
+	EnctypeDesCbcCrc:              "des-cbc-crc",
+	EnctypeDesCbcMd4:              "des-cbc-md4",
+	EnctypeDesCbcMd5:              "des-cbc-md5",
+	EnctypeDes3CbcSha1:            "des3-cbc-sha1",
+	EnctypeAes128CtsHmacSha196:    "aes128-cts-hmac-sha1-96",
+	EnctypeAes256CtsHmacSha196:    "aes256-cts-hmac-sha1-96",
+	EnctypeAes128CtsHmacSha256128: "aes128-cts-hmac-sha256-128",
+	EnctypeAes256CtsHmacSha384192: "aes256-cts-hmac-sha384-192",
+	EnctypeArcfourHmac:            "arcfour-hmac",
+	EnctypeArcfourHmacExp:         "arcfour-hmac-exp",
+	EnctypeCamellia128CtsCmac:     "camellia128-cts-cmac",
+	EnctypeCamellia256CtsCmac:     "camellia256-cts-cmac",
+}
+
+// EnctypeName returns the name used by MIT Kerberos for a Kerberos encryption type,
+// or the number if it is not known.
+func EnctypeName(etype int32) string {
+	if name, ok := enctypeNames[etype]; ok {
+		return name
+	}
+
+	return strconv.Itoa(int(etype))
+}
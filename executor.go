@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"context"
+	"runtime"
+)
+
+// runCancelable runs call on its own OS thread and waits for it to finish or for ctx to
+// be done, whichever happens first.  The GSSAPI C calls cannot be interrupted, so if ctx
+// wins the call is left to run to completion in the background and its result is handed
+// to abandon, which must release anything the call allocated.
+//
+// started reports whether call was run at all: it is not if ctx is already done, and then
+// neither call nor abandon has touched anything.
+func runCancelable[T any](ctx context.Context, call func() T, abandon func(T)) (ret T, started bool, err error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, false, err
+	}
+
+	done := make(chan T, 1)
+	go func() {
+		// keep any thread-local library state consistent for the duration of the call
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		done <- call()
+	}()
+
+	select {
+	case ret := <-done:
+		return ret, true, nil
+	case <-ctx.Done():
+		// prefer a result that raced with the cancellation
+		select {
+		case ret := <-done:
+			return ret, true, nil
+		default:
+		}
+
+		go func() {
+			abandon(<-done)
+		}()
+		return zero, true, ctx.Err()
+	}
+}
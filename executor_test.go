@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunCancelable(t *testing.T) {
+	assert := NewAssert(t)
+
+	// call completes
+	ret, started, err := runCancelable(context.Background(), func() int { return 42 }, func(int) { t.Error("unexpected abandon") })
+	assert.NoError(err)
+	assert.True(started)
+	assert.Equal(42, ret)
+
+	// context already cancelled: the call should not be started
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	_, started, err = runCancelable(ctx, func() int { called = true; return 1 }, func(int) {})
+	assert.ErrorIs(err, context.Canceled)
+	assert.False(started)
+	assert.False(called)
+
+	// deadline passes while the call is blocked: the result goes to abandon
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	release := make(chan struct{})
+	abandoned := make(chan int, 1)
+	_, started, err = runCancelable(ctx, func() int { <-release; return 7 }, func(v int) { abandoned <- v })
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.True(started)
+
+	close(release)
+	select {
+	case v := <-abandoned:
+		assert.Equal(7, v)
+	case <-time.After(5 * time.Second):
+		t.Error("abandoned result was not cleaned up")
+	}
+}
@@ -1,13 +1,24 @@
 #include "gss.h"
+#include <pthread.h>
 
 int has_channel_bound() {
-#if defined(GSS_C_CHANNEL_BOUND_FLAG)
+#if defined(GOGSSAPI_DLOPEN)
+	return _gogssapi_dl_has_channel_bound;
+#elif defined(GSS_C_CHANNEL_BOUND_FLAG)
 	return 1;
 #else
 	return 0;
 #endif
 }
 
+int is_heimdal() {
+#if defined(GOGSSAPI_DLOPEN)
+	return _gogssapi_dl_is_heimdal;
+#else
+	return IS_HEIMDAL;
+#endif
+}
+
 int is_mac_framework() {
 #if defined(OSX_HAS_GSS_FRAMEWORK)
 	return 1;
@@ -17,3 +28,78 @@ int is_mac_framework() {
 }
 
 gss_buffer_desc gss_empty_buffer = GSS_C_EMPTY_BUFFER;
+
+// Set by Go when calls into the library must be serialized.  The lock is recursive so that
+// helpers holding it can make further locked calls.
+static int serialize_calls = 0;
+static pthread_mutex_t call_mutex;
+static pthread_once_t call_mutex_once = PTHREAD_ONCE_INIT;
+
+static void init_call_mutex() {
+	pthread_mutexattr_t attr;
+	pthread_mutexattr_init(&attr);
+	pthread_mutexattr_settype(&attr, PTHREAD_MUTEX_RECURSIVE);
+	pthread_mutex_init(&call_mutex, &attr);
+	pthread_mutexattr_destroy(&attr);
+}
+
+void _gogssapi_set_serialize_calls(int serialize) {
+	pthread_once(&call_mutex_once, init_call_mutex);
+	__atomic_store_n(&serialize_calls, serialize, __ATOMIC_SEQ_CST);
+}
+
+// Returns 1 if the lock was taken, to be passed to _gogssapi_unlock.  Calls that started
+// before serialization was turned on are not waited for.
+int _gogssapi_lock() {
+	if( ! __atomic_load_n(&serialize_calls, __ATOMIC_SEQ_CST) ) {
+		return 0;
+	}
+	pthread_mutex_lock(&call_mutex);
+	return 1;
+}
+
+void _gogssapi_unlock(int locked) {
+	if( locked ) {
+		pthread_mutex_unlock(&call_mutex);
+	}
+}
+
+#if defined(GOGSSAPI_DLOPEN)
+int _gogssapi_dl_is_heimdal = 0;
+int _gogssapi_dl_has_channel_bound = 0;
+
+_GOGSSAPI_DL_DEFINE(gss_accept_sec_context);
+_GOGSSAPI_DL_DEFINE(gss_acquire_cred);
+_GOGSSAPI_DL_DEFINE(gss_add_cred);
+_GOGSSAPI_DL_DEFINE(gss_add_oid_set_member);
+_GOGSSAPI_DL_DEFINE(gss_canonicalize_name);
+_GOGSSAPI_DL_DEFINE(gss_compare_name);
+_GOGSSAPI_DL_DEFINE(gss_context_time);
+_GOGSSAPI_DL_DEFINE(gss_create_empty_oid_set);
+_GOGSSAPI_DL_DEFINE(gss_delete_sec_context);
+_GOGSSAPI_DL_DEFINE(gss_display_name);
+_GOGSSAPI_DL_DEFINE(gss_display_status);
+_GOGSSAPI_DL_DEFINE(gss_duplicate_name);
+_GOGSSAPI_DL_DEFINE(gss_export_name);
+_GOGSSAPI_DL_DEFINE(gss_export_sec_context);
+_GOGSSAPI_DL_DEFINE(gss_get_mic);
+_GOGSSAPI_DL_DEFINE(gss_import_name);
+_GOGSSAPI_DL_DEFINE(gss_import_sec_context);
+_GOGSSAPI_DL_DEFINE(gss_indicate_mechs);
+_GOGSSAPI_DL_DEFINE(gss_init_sec_context);
+_GOGSSAPI_DL_DEFINE(gss_inquire_context);
+_GOGSSAPI_DL_DEFINE(gss_inquire_cred);
+_GOGSSAPI_DL_DEFINE(gss_inquire_cred_by_mech);
+_GOGSSAPI_DL_DEFINE(gss_inquire_mechs_for_name);
+_GOGSSAPI_DL_DEFINE(gss_inquire_names_for_mech);
+_GOGSSAPI_DL_DEFINE(gss_process_context_token);
+_GOGSSAPI_DL_DEFINE(gss_release_buffer);
+_GOGSSAPI_DL_DEFINE(gss_release_buffer_set);
+_GOGSSAPI_DL_DEFINE(gss_release_cred);
+_GOGSSAPI_DL_DEFINE(gss_release_name);
+_GOGSSAPI_DL_DEFINE(gss_release_oid_set);
+_GOGSSAPI_DL_DEFINE(gss_unwrap);
+_GOGSSAPI_DL_DEFINE(gss_verify_mic);
+_GOGSSAPI_DL_DEFINE(gss_wrap);
+_GOGSSAPI_DL_DEFINE(gss_wrap_size_limit);
+#endif
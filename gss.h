@@ -40,5 +40,60 @@ typedef struct gss_buffer_set_desc_struct {
 
 extern gss_buffer_desc gss_empty_buffer;
 extern int has_channel_bound();
+extern int is_heimdal();
 extern int is_mac_framework();
 
+// Calls into the GSSAPI library are made through these macros so that they can be
+// serialized when the library is not thread safe (see threadsafe.go).  _GOGSSAPI_FN
+// names the function, or the pointer to it when the library is opened at run time.
+extern void _gogssapi_set_serialize_calls(int serialize);
+extern int _gogssapi_lock();
+extern void _gogssapi_unlock(int locked);
+
+#define _GOGSSAPI_LOCKED(call) ({ \
+	int _gogssapi_locked = _gogssapi_lock(); \
+	__typeof__(call) _gogssapi_ret = (call); \
+	_gogssapi_unlock(_gogssapi_locked); \
+	_gogssapi_ret; \
+})
+
+#if defined(GOGSSAPI_DLOPEN)
+#include "gss_dlopen.h"
+#else
+#define _GOGSSAPI_FN(fn) (fn)
+#endif
+
+#define gss_accept_sec_context(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_accept_sec_context)(__VA_ARGS__))
+#define gss_acquire_cred(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_acquire_cred)(__VA_ARGS__))
+#define gss_add_cred(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_add_cred)(__VA_ARGS__))
+#define gss_add_oid_set_member(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_add_oid_set_member)(__VA_ARGS__))
+#define gss_canonicalize_name(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_canonicalize_name)(__VA_ARGS__))
+#define gss_compare_name(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_compare_name)(__VA_ARGS__))
+#define gss_context_time(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_context_time)(__VA_ARGS__))
+#define gss_create_empty_oid_set(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_create_empty_oid_set)(__VA_ARGS__))
+#define gss_delete_sec_context(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_delete_sec_context)(__VA_ARGS__))
+#define gss_display_name(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_display_name)(__VA_ARGS__))
+#define gss_display_status(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_display_status)(__VA_ARGS__))
+#define gss_duplicate_name(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_duplicate_name)(__VA_ARGS__))
+#define gss_export_name(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_export_name)(__VA_ARGS__))
+#define gss_export_sec_context(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_export_sec_context)(__VA_ARGS__))
+#define gss_get_mic(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_get_mic)(__VA_ARGS__))
+#define gss_import_name(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_import_name)(__VA_ARGS__))
+#define gss_import_sec_context(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_import_sec_context)(__VA_ARGS__))
+#define gss_indicate_mechs(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_indicate_mechs)(__VA_ARGS__))
+#define gss_init_sec_context(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_init_sec_context)(__VA_ARGS__))
+#define gss_inquire_context(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_inquire_context)(__VA_ARGS__))
+#define gss_inquire_cred(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_inquire_cred)(__VA_ARGS__))
+#define gss_inquire_cred_by_mech(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_inquire_cred_by_mech)(__VA_ARGS__))
+#define gss_inquire_mechs_for_name(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_inquire_mechs_for_name)(__VA_ARGS__))
+#define gss_inquire_names_for_mech(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_inquire_names_for_mech)(__VA_ARGS__))
+#define gss_process_context_token(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_process_context_token)(__VA_ARGS__))
+#define gss_release_buffer(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_release_buffer)(__VA_ARGS__))
+#define gss_release_buffer_set(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_release_buffer_set)(__VA_ARGS__))
+#define gss_release_cred(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_release_cred)(__VA_ARGS__))
+#define gss_release_name(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_release_name)(__VA_ARGS__))
+#define gss_release_oid_set(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_release_oid_set)(__VA_ARGS__))
+#define gss_unwrap(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_unwrap)(__VA_ARGS__))
+#define gss_verify_mic(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_verify_mic)(__VA_ARGS__))
+#define gss_wrap(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_wrap)(__VA_ARGS__))
+#define gss_wrap_size_limit(...) _GOGSSAPI_LOCKED(_GOGSSAPI_FN(gss_wrap_size_limit)(__VA_ARGS__))
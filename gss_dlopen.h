@@ -0,0 +1,49 @@
+// gss_dlopen.h is included by gss.h in place of linking to a GSSAPI library when the
+// usedlopen build tag is supplied.  It declares a pointer for each GSSAPI function that the
+// provider calls, which is set by loadLibrary() once the library has been opened with
+// dlopen.  The call macros in gss.h go through the pointers using _GOGSSAPI_FN.
+//
+// Functions added to this file must also be added to coreSymbols in provider_dlopen.go,
+// defined in gss.c and given a call macro in gss.h.
+
+#define _GOGSSAPI_FN(fn) (*_gogssapi_dl_##fn)
+#define _GOGSSAPI_DL_DECLARE(fn) extern __typeof__(fn) *_gogssapi_dl_##fn
+#define _GOGSSAPI_DL_DEFINE(fn) __typeof__(fn) *_gogssapi_dl_##fn = NULL
+
+extern int _gogssapi_dl_is_heimdal;
+extern int _gogssapi_dl_has_channel_bound;
+
+_GOGSSAPI_DL_DECLARE(gss_accept_sec_context);
+_GOGSSAPI_DL_DECLARE(gss_acquire_cred);
+_GOGSSAPI_DL_DECLARE(gss_add_cred);
+_GOGSSAPI_DL_DECLARE(gss_add_oid_set_member);
+_GOGSSAPI_DL_DECLARE(gss_canonicalize_name);
+_GOGSSAPI_DL_DECLARE(gss_compare_name);
+_GOGSSAPI_DL_DECLARE(gss_context_time);
+_GOGSSAPI_DL_DECLARE(gss_create_empty_oid_set);
+_GOGSSAPI_DL_DECLARE(gss_delete_sec_context);
+_GOGSSAPI_DL_DECLARE(gss_display_name);
+_GOGSSAPI_DL_DECLARE(gss_display_status);
+_GOGSSAPI_DL_DECLARE(gss_duplicate_name);
+_GOGSSAPI_DL_DECLARE(gss_export_name);
+_GOGSSAPI_DL_DECLARE(gss_export_sec_context);
+_GOGSSAPI_DL_DECLARE(gss_get_mic);
+_GOGSSAPI_DL_DECLARE(gss_import_name);
+_GOGSSAPI_DL_DECLARE(gss_import_sec_context);
+_GOGSSAPI_DL_DECLARE(gss_indicate_mechs);
+_GOGSSAPI_DL_DECLARE(gss_init_sec_context);
+_GOGSSAPI_DL_DECLARE(gss_inquire_context);
+_GOGSSAPI_DL_DECLARE(gss_inquire_cred);
+_GOGSSAPI_DL_DECLARE(gss_inquire_cred_by_mech);
+_GOGSSAPI_DL_DECLARE(gss_inquire_mechs_for_name);
+_GOGSSAPI_DL_DECLARE(gss_inquire_names_for_mech);
+_GOGSSAPI_DL_DECLARE(gss_process_context_token);
+_GOGSSAPI_DL_DECLARE(gss_release_buffer);
+_GOGSSAPI_DL_DECLARE(gss_release_buffer_set);
+_GOGSSAPI_DL_DECLARE(gss_release_cred);
+_GOGSSAPI_DL_DECLARE(gss_release_name);
+_GOGSSAPI_DL_DECLARE(gss_release_oid_set);
+_GOGSSAPI_DL_DECLARE(gss_unwrap);
+_GOGSSAPI_DL_DECLARE(gss_verify_mic);
+_GOGSSAPI_DL_DECLARE(gss_wrap);
+_GOGSSAPI_DL_DECLARE(gss_wrap_size_limit);
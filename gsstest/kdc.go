@@ -0,0 +1,651 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package gsstest runs a throw-away Kerberos KDC for tests.
+//
+// A KDC started by [Start] listens on the loopback interface only and keeps all of its state
+// (database, stash file, configuration, keytabs and credential caches) in a temporary
+// directory that is removed when the KDC is closed.  Either MIT Kerberos (krb5kdc) or
+// Heimdal (kdc) can be used, so that end-to-end flows such as TGS requests, renewal and
+// delegation can be exercised without network access or a site KDC.
+package gsstest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Implementation identifies the Kerberos KDC implementation used by a test KDC.
+type Implementation int
+
+const (
+	// ImplAuto picks MIT Kerberos if its KDC binaries are installed, otherwise Heimdal.
+	ImplAuto Implementation = iota
+	// ImplMIT selects the MIT Kerberos krb5kdc.
+	ImplMIT
+	// ImplHeimdal selects the Heimdal kdc.
+	ImplHeimdal
+)
+
+func (i Implementation) String() string {
+	switch i {
+	default:
+		return "auto"
+	case ImplMIT:
+		return "MIT"
+	case ImplHeimdal:
+		return "Heimdal"
+	}
+}
+
+// ErrNoKDC is returned by [Start] when the binaries for the requested KDC implementation
+// cannot be found.
+var ErrNoKDC = errors.New("gsstest: no usable Kerberos KDC found")
+
+// the principal the KDC issues anonymous tickets to
+const anonymousPrincipal = "WELLKNOWN/ANONYMOUS"
+
+// DefaultRealm is the realm used when no realm is configured with [WithRealm].
+const DefaultRealm = "GSSTEST.GOLANG-AUTH.IO"
+
+// Directories searched for KDC binaries in addition to PATH.  The KDC daemons are often
+// installed outside of the default search path.
+var searchDirs = []string{
+	"/usr/sbin",
+	"/usr/local/sbin",
+	"/usr/libexec",
+	"/usr/local/libexec",
+	"/usr/lib/heimdal-servers",
+	"/usr/libexec/heimdal",
+	"/usr/heimdal/libexec",
+	"/usr/local/heimdal/libexec",
+	"/usr/local/heimdal/bin",
+	"/usr/local/heimdal/sbin",
+	"/opt/homebrew/opt/krb5/sbin",
+	"/opt/homebrew/opt/krb5/bin",
+	"/opt/homebrew/opt/heimdal/libexec/heimdal",
+	"/opt/homebrew/opt/heimdal/bin",
+}
+
+type options struct {
+	realm     string
+	impl      Implementation
+	maxLife   time.Duration
+	keep      bool
+	anonymous bool
+}
+
+// Option configures a test KDC.
+type Option func(o *options)
+
+// WithRealm sets the realm served by the KDC.  The default is [DefaultRealm].
+func WithRealm(realm string) Option {
+	return func(o *options) {
+		o.realm = realm
+	}
+}
+
+// WithImplementation selects the KDC implementation.  The default is [ImplAuto].
+func WithImplementation(impl Implementation) Option {
+	return func(o *options) {
+		o.impl = impl
+	}
+}
+
+// WithMaxLife sets the maximum ticket and renewable lifetime for the realm.  The default is
+// one day.
+func WithMaxLife(d time.Duration) Option {
+	return func(o *options) {
+		o.maxLife = d
+	}
+}
+
+// WithAnonymous enables anonymous PKINIT (RFC 8062) so that clients can obtain tickets for
+// the WELLKNOWN/ANONYMOUS principal.  A CA and KDC certificate are generated for the realm and
+// the client configuration trusts them.  MIT Kerberos needs its PKINIT plugin to be installed.
+func WithAnonymous() Option {
+	return func(o *options) {
+		o.anonymous = true
+	}
+}
+
+// WithKeepDir prevents the temporary directory from being removed by [KDC.Close], which is
+// handy when debugging a failing test.
+func WithKeepDir() Option {
+	return func(o *options) {
+		o.keep = true
+	}
+}
+
+// KDC is a running test KDC.  All methods are safe to call from multiple goroutines.
+// Changes to the database are made one at a time because the admin tools do not support
+// concurrent use; methods called once Close has started return errors.
+type KDC struct {
+	mu     sync.Mutex // serializes kadmin and Close
+	closed bool
+
+	realm string
+	impl  Implementation
+	dir   string
+	port  int
+	keep  bool
+	anon  bool
+
+	krb5Conf   string
+	kdcConf    string
+	masterPass string
+
+	bins map[string]string
+	cmd  *exec.Cmd
+	done chan struct{}
+}
+
+// Start creates a KDC database in a new temporary directory and starts a KDC on the
+// loopback interface.  The caller must call [KDC.Close] to stop the KDC and remove
+// its files.  [ErrNoKDC] is returned if no KDC implementation is installed.
+func Start(opts ...Option) (*KDC, error) {
+	o := options{
+		realm:   DefaultRealm,
+		maxLife: 24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	impl, bins, err := findImplementation(o.impl)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "gsstest")
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := freePort()
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+
+	k := &KDC{
+		realm:      o.realm,
+		impl:       impl,
+		dir:        dir,
+		port:       port,
+		keep:       o.keep,
+		anon:       o.anonymous,
+		krb5Conf:   filepath.Join(dir, "krb5.conf"),
+		kdcConf:    filepath.Join(dir, "kdc.conf"),
+		masterPass: "gsstest-master",
+		bins:       bins,
+	}
+
+	if k.anon {
+		err = k.writePKINITIdentity()
+	}
+	if err == nil {
+		err = k.writeConfig(o.maxLife)
+	}
+	if err == nil {
+		err = k.createDatabase()
+	}
+	if err == nil {
+		err = k.startKDC()
+	}
+	if err != nil {
+		_ = k.Close()
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Realm returns the realm served by the KDC.
+func (k *KDC) Realm() string {
+	return k.realm
+}
+
+// Implementation returns the KDC implementation in use.
+func (k *KDC) Implementation() Implementation {
+	return k.impl
+}
+
+// Dir returns the temporary directory holding the KDC state.  Callers may create their
+// own files in this directory; they are removed by [KDC.Close].
+func (k *KDC) Dir() string {
+	return k.dir
+}
+
+// Addr returns the loopback address the KDC listens on for both UDP and TCP.
+func (k *KDC) Addr() string {
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(k.port))
+}
+
+// Krb5Conf returns the path of a krb5.conf that points clients at the KDC.
+func (k *KDC) Krb5Conf() string {
+	return k.krb5Conf
+}
+
+// Env returns the environment variables that make the Kerberos libraries use the KDC's
+// configuration, in "KEY=value" form.
+func (k *KDC) Env() []string {
+	return []string{
+		"KRB5_CONFIG=" + k.krb5Conf,
+		"KRB5_KDC_PROFILE=" + k.kdcConf,
+	}
+}
+
+// Principal qualifies name with the KDC realm if it does not already contain one.
+func (k *KDC) Principal(name string) string {
+	if strings.Contains(name, "@") {
+		return name
+	}
+	return name + "@" + k.realm
+}
+
+// AddPrincipal creates a principal with the given password.
+func (k *KDC) AddPrincipal(name, password string) error {
+	princ := k.Principal(name)
+	if k.impl == ImplHeimdal {
+		return k.kadmin("add", "--password="+password, "--use-defaults", princ)
+	}
+	return k.kadmin("addprinc", "-pw", password, princ)
+}
+
+// AddRandomKeyPrincipal creates a principal with random keys, typically a service
+// principal such as "HTTP/host.example.com".
+func (k *KDC) AddRandomKeyPrincipal(name string) error {
+	princ := k.Principal(name)
+	if k.impl == ImplHeimdal {
+		return k.kadmin("add", "--random-key", "--use-defaults", princ)
+	}
+	return k.kadmin("addprinc", "-randkey", princ)
+}
+
+// WriteKeytab extracts the current keys of the principals into the keytab file at path,
+// which may already exist.  The keys are not changed by the extraction.
+func (k *KDC) WriteKeytab(path string, principals ...string) error {
+	for _, p := range principals {
+		princ := k.Principal(p)
+		var err error
+		if k.impl == ImplHeimdal {
+			err = k.kadmin("ext_keytab", "--keytab="+path, princ)
+		} else {
+			err = k.kadmin("ktadd", "-k", path, "-norandkey", princ)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Keytab is like [KDC.WriteKeytab] but writes to a new file in the KDC directory and
+// returns its name.
+func (k *KDC) Keytab(principals ...string) (string, error) {
+	path, err := k.tmpName("keytab")
+	if err != nil {
+		return "", err
+	}
+
+	return path, k.WriteKeytab(path, principals...)
+}
+
+// KinitPassword obtains a TGT for principal into the FILE credential cache at path
+// using the kinit command.  Additional kinit arguments such as "-f" (forwardable) or
+// "-r" (renewable) can be supplied in args.
+func (k *KDC) KinitPassword(path, principal, password string, args ...string) error {
+	argv := append([]string{"-c", "FILE:" + path}, args...)
+	if k.impl == ImplHeimdal {
+		argv = append(argv, "--password-file=STDIN")
+	}
+	argv = append(argv, k.Principal(principal))
+
+	return k.run(k.bins["kinit"], strings.NewReader(password+"\n"), argv...)
+}
+
+// KinitKeytab obtains a TGT for principal into the FILE credential cache at path using
+// keys from keytab.
+func (k *KDC) KinitKeytab(path, principal, keytab string, args ...string) error {
+	argv := append([]string{"-c", "FILE:" + path}, args...)
+	if k.impl == ImplHeimdal {
+		argv = append(argv, "--keytab="+keytab)
+	} else {
+		argv = append(argv, "-k", "-t", keytab)
+	}
+	argv = append(argv, k.Principal(principal))
+
+	return k.run(k.bins["kinit"], nil, argv...)
+}
+
+// CCache is like [KDC.KinitPassword] but writes to a new file in the KDC directory and
+// returns its name.
+func (k *KDC) CCache(principal, password string, args ...string) (string, error) {
+	path, err := k.tmpName("ccache")
+	if err != nil {
+		return "", err
+	}
+
+	return path, k.KinitPassword(path, principal, password, args...)
+}
+
+// Close stops the KDC and removes its temporary directory.
+func (k *KDC) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.closed = true
+
+	var errs []error
+	if k.cmd != nil && k.cmd.Process != nil {
+		_ = k.cmd.Process.Kill()
+		<-k.done
+		k.cmd = nil
+	}
+
+	if !k.keep {
+		errs = append(errs, os.RemoveAll(k.dir))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (k *KDC) tmpName(prefix string) (string, error) {
+	fh, err := os.CreateTemp(k.dir, prefix)
+	if err != nil {
+		return "", err
+	}
+	name := fh.Name()
+	_ = fh.Close()
+
+	// the Kerberos tools refuse to write to an empty keytab or ccache file
+	return name, os.Remove(name)
+}
+
+func (k *KDC) kadmin(args ...string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.closed {
+		return errors.New("gsstest: the KDC has been closed")
+	}
+	if k.impl == ImplHeimdal {
+		argv := append([]string{"--config-file=" + k.krb5Conf, "-l"}, args...)
+		return k.run(k.bins["kadmin"], nil, argv...)
+	}
+
+	return k.run(k.bins["kadmin.local"], nil, "-r", k.realm, "-q", strings.Join(quoteArgs(args), " "))
+}
+
+func (k *KDC) run(bin string, stdin *strings.Reader, args ...string) error {
+	cmd := exec.Command(bin, args...)
+	cmd.Env = append(os.Environ(), k.Env()...)
+	cmd.Dir = k.dir
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("gsstest: %s %s: %w: %s", filepath.Base(bin), strings.Join(args, " "), err, strings.TrimSpace(out.String()))
+	}
+
+	// kadmin.local reports most failures on stderr but still exits zero
+	if k.impl == ImplMIT && filepath.Base(bin) == "kadmin.local" {
+		for _, line := range strings.Split(out.String(), "\n") {
+			if strings.Contains(line, " while ") {
+				return fmt.Errorf("gsstest: kadmin.local %s: %s", strings.Join(args, " "), strings.TrimSpace(line))
+			}
+		}
+	}
+
+	return nil
+}
+
+func quoteArgs(args []string) []string {
+	ret := make([]string, len(args))
+	for i, a := range args {
+		if strings.ContainsAny(a, " \t\"") {
+			a = `"` + strings.ReplaceAll(a, `"`, `\"`) + `"`
+		}
+		ret[i] = a
+	}
+	return ret
+}
+
+func (k *KDC) createDatabase() error {
+	if k.impl == ImplHeimdal {
+		if err := k.run(k.bins["kstash"], nil, "--config-file="+k.krb5Conf, "--random-key", "--key-file="+filepath.Join(k.dir, "m-key")); err != nil {
+			return err
+		}
+		if err := k.kadmin("init", "--realm-max-ticket-life=unlimited", "--realm-max-renewable-life=unlimited", k.realm); err != nil {
+			return err
+		}
+		// recent releases create the anonymous principal with the realm
+		if k.anon && k.kadmin("get", k.Principal(anonymousPrincipal)) != nil {
+			return k.AddRandomKeyPrincipal(anonymousPrincipal)
+		}
+		return nil
+	}
+
+	if err := k.run(k.bins["kdb5_util"], nil, "create", "-r", k.realm, "-s", "-P", k.masterPass); err != nil {
+		return err
+	}
+	if k.anon {
+		return k.AddRandomKeyPrincipal(anonymousPrincipal)
+	}
+	return nil
+}
+
+func (k *KDC) startKDC() error {
+	var cmd *exec.Cmd
+	if k.impl == ImplHeimdal {
+		cmd = exec.Command(k.bins["kdc"], "--config-file="+k.krb5Conf, "--addresses=127.0.0.1", "--ports="+strconv.Itoa(k.port))
+	} else {
+		cmd = exec.Command(k.bins["krb5kdc"], "-n", "-r", k.realm)
+	}
+	cmd.Env = append(os.Environ(), k.Env()...)
+	cmd.Dir = k.dir
+
+	logFile, err := os.Create(filepath.Join(k.dir, "kdc.stderr"))
+	if err != nil {
+		return err
+	}
+	defer logFile.Close() //nolint:errcheck
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("gsstest: starting KDC: %w", err)
+	}
+
+	k.cmd = cmd
+	k.done = make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(k.done)
+	}()
+
+	// wait for the KDC to accept connections
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		select {
+		case <-k.done:
+			logData, _ := os.ReadFile(filepath.Join(k.dir, "kdc.stderr"))
+			k.cmd = nil
+			return fmt.Errorf("gsstest: KDC exited during startup: %s", strings.TrimSpace(string(logData)))
+		default:
+		}
+
+		conn, err := net.DialTimeout("tcp", k.Addr(), 100*time.Millisecond)
+		if err == nil {
+			_ = conn.Close()
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return fmt.Errorf("gsstest: KDC did not start listening on %s", k.Addr())
+}
+
+func (k *KDC) writeConfig(maxLife time.Duration) error {
+	life := strconv.Itoa(int(maxLife.Seconds())) + "s"
+	logFile := filepath.Join(k.dir, "kdc.log")
+
+	// PKINIT settings for anonymous tickets, indented to suit each section
+	var pkinitAnchors string
+	kdcPKINIT := func(indent string) string { return "" }
+	if k.anon {
+		pkinitAnchors = "\tpkinit_anchors = " + k.PKINITAnchors() + "\n"
+		kdcPKINIT = func(indent string) string {
+			return indent + "pkinit_identity = " + k.pkinitIdentity() + "\n" + indent + "pkinit_anchors = " + k.PKINITAnchors() + "\n"
+		}
+	}
+
+	krb5Conf := fmt.Sprintf(`[libdefaults]
+	default_realm = %[1]s
+	dns_lookup_realm = false
+	dns_lookup_kdc = false
+	rdns = false
+	ignore_acceptor_hostname = false
+	forwardable = true
+	udp_preference_limit = 1
+%[4]s
+[realms]
+	%[1]s = {
+		kdc = %[2]s
+		admin_server = %[2]s
+	}
+
+[logging]
+	kdc = FILE:%[3]s
+	default = FILE:%[3]s
+`, k.realm, k.Addr(), logFile, pkinitAnchors)
+
+	if k.impl == ImplHeimdal {
+		krb5Conf += fmt.Sprintf(`
+[kdc]
+	database = {
+		dbname = %[1]s
+		realm = %[2]s
+		mkey_file = %[3]s
+		acl_file = %[4]s
+		log_file = %[5]s
+	}
+	max-kdc-datagram-reply-length = 65535
+	enable-pkinit = %[6]t
+	allow-anonymous = %[6]t
+%[7]s`, filepath.Join(k.dir, "heimdal"), k.realm, filepath.Join(k.dir, "m-key"), filepath.Join(k.dir, "kadmind.acl"), filepath.Join(k.dir, "log"), k.anon, kdcPKINIT("\t"))
+	}
+
+	if err := os.WriteFile(k.krb5Conf, []byte(krb5Conf), 0600); err != nil {
+		return err
+	}
+
+	kdcConf := fmt.Sprintf(`[kdcdefaults]
+	kdc_listen = 127.0.0.1:%[2]d
+	kdc_tcp_listen = 127.0.0.1:%[2]d
+
+[realms]
+	%[1]s = {
+		database_name = %[3]s
+		key_stash_file = %[4]s
+		acl_file = %[5]s
+		max_life = %[6]s
+		max_renewable_life = %[6]s
+		supported_enctypes = aes256-cts:normal aes128-cts:normal
+%[8]s	}
+
+[logging]
+	kdc = FILE:%[7]s
+`, k.realm, k.port, filepath.Join(k.dir, "principal"), filepath.Join(k.dir, "stash"), filepath.Join(k.dir, "kadm5.acl"), life, logFile, kdcPKINIT("\t\t"))
+
+	if err := os.WriteFile(k.kdcConf, []byte(kdcConf), 0600); err != nil {
+		return err
+	}
+
+	acl := filepath.Join(k.dir, "kadm5.acl")
+	if k.impl == ImplHeimdal {
+		acl = filepath.Join(k.dir, "kadmind.acl")
+	}
+	return os.WriteFile(acl, []byte("*/admin@"+k.realm+" *\n"), 0600)
+}
+
+// freePort returns a port number that is currently free for both TCP and UDP on the
+// loopback interface.
+func freePort() (int, error) {
+	for range 20 {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return 0, err
+		}
+		port := l.Addr().(*net.TCPAddr).Port
+		_ = l.Close()
+
+		u, err := net.ListenPacket("udp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		if err != nil {
+			continue
+		}
+		_ = u.Close()
+
+		return port, nil
+	}
+
+	return 0, errors.New("gsstest: could not find a free port")
+}
+
+func lookBinary(name string) string {
+	if p, err := exec.LookPath(name); err == nil {
+		return p
+	}
+
+	for _, dir := range searchDirs {
+		p := filepath.Join(dir, name)
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() && fi.Mode()&0111 != 0 {
+			return p
+		}
+	}
+
+	return ""
+}
+
+func findBinaries(names ...string) (map[string]string, bool) {
+	ret := make(map[string]string, len(names))
+	for _, name := range names {
+		p := lookBinary(name)
+		if p == "" {
+			return nil, false
+		}
+		ret[name] = p
+	}
+
+	return ret, true
+}
+
+func findImplementation(impl Implementation) (Implementation, map[string]string, error) {
+	if impl == ImplAuto || impl == ImplMIT {
+		if bins, ok := findBinaries("krb5kdc", "kdb5_util", "kadmin.local", "kinit"); ok {
+			return ImplMIT, bins, nil
+		}
+	}
+
+	if impl == ImplAuto || impl == ImplHeimdal {
+		if bins, ok := findBinaries("kdc", "kstash", "kadmin", "kinit"); ok {
+			return ImplHeimdal, bins, nil
+		}
+	}
+
+	return impl, nil, fmt.Errorf("%w (%s)", ErrNoKDC, impl)
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gsstest
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImplementationString(t *testing.T) {
+	assert.Equal(t, "auto", ImplAuto.String())
+	assert.Equal(t, "MIT", ImplMIT.String())
+	assert.Equal(t, "Heimdal", ImplHeimdal.String())
+}
+
+func TestFreePort(t *testing.T) {
+	port, err := freePort()
+	assert.NoError(t, err)
+	assert.Greater(t, port, 0)
+}
+
+func TestQuoteArgs(t *testing.T) {
+	assert.Equal(t, []string{"addprinc", `"a b"`, `"x\"y z"`}, quoteArgs([]string{"addprinc", "a b", `x"y z`}))
+}
+
+func TestFindImplementationMissing(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	saved := searchDirs
+	searchDirs = nil
+	defer func() { searchDirs = saved }()
+
+	_, _, err := findImplementation(ImplAuto)
+	assert.True(t, errors.Is(err, ErrNoKDC))
+}
+
+func TestClosedKDC(t *testing.T) {
+	k := &KDC{realm: DefaultRealm, dir: t.TempDir(), keep: true}
+	assert.NoError(t, k.Close())
+	assert.Error(t, k.AddRandomKeyPrincipal("HTTP/www.golang-auth.io"))
+}
+
+func TestKDC(t *testing.T) {
+	kdc := StartT(t)
+
+	assert.Equal(t, DefaultRealm, kdc.Realm())
+	assert.Equal(t, "robot@"+DefaultRealm, kdc.Principal("robot"))
+	assert.Equal(t, "robot@OTHER", kdc.Principal("robot@OTHER"))
+
+	assert.NoError(t, kdc.AddPrincipal("robot", "password"))
+	assert.NoError(t, kdc.AddRandomKeyPrincipal("HTTP/www.golang-auth.io"))
+
+	kt, err := kdc.Keytab("HTTP/www.golang-auth.io")
+	if assert.NoError(t, err) {
+		fi, err := os.Stat(kt)
+		assert.NoError(t, err)
+		assert.Greater(t, fi.Size(), int64(0))
+	}
+
+	cc, err := kdc.CCache("robot", "password")
+	if assert.NoError(t, err) {
+		fi, err := os.Stat(cc)
+		assert.NoError(t, err)
+		assert.Greater(t, fi.Size(), int64(0))
+	}
+
+	_, err = kdc.CCache("robot", "wrong-password")
+	assert.Error(t, err)
+
+	dir := kdc.Dir()
+	assert.NoError(t, kdc.Close())
+	_, err = os.Stat(dir)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
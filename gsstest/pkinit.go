@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gsstest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// id-pkinit-san, the otherName form of a Kerberos principal (RFC 4556 § 3.2.2)
+	oidPKINITSan = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 2, 2}
+	// id-pkinit-KPKdc, the extended key usage of a KDC certificate (RFC 4556 § 3.2.4)
+	oidPKINITKPKdc = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 2, 3, 5}
+	// id-ce-subjectAltName
+	oidSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+)
+
+// Files making up the PKINIT identity of the KDC, in the KDC directory
+const (
+	pkinitCAFile   = "pkinit-ca.pem"
+	pkinitCertFile = "pkinit-kdc.pem"
+	pkinitKeyFile  = "pkinit-kdc-key.pem"
+)
+
+// PKINITAnchors returns the trust anchors for the KDC certificate in the form used by the
+// pkinit_anchors setting of krb5.conf.  It is only meaningful for a KDC started with
+// [WithAnonymous].
+func (k *KDC) PKINITAnchors() string {
+	return "FILE:" + filepath.Join(k.dir, pkinitCAFile)
+}
+
+func (k *KDC) pkinitIdentity() string {
+	return "FILE:" + filepath.Join(k.dir, pkinitCertFile) + "," + filepath.Join(k.dir, pkinitKeyFile)
+}
+
+// writePKINITIdentity creates a CA and a KDC certificate for krbtgt/REALM@REALM, as required
+// for anonymous PKINIT.  The keys are generated afresh for every KDC.
+func (k *KDC) writePKINITIdentity() error {
+	now := time.Now()
+
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"gsstest"}, CommonName: "gsstest CA " + k.realm},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(7 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return err
+	}
+
+	san, err := pkinitSan("krbtgt/"+k.realm, k.realm)
+	if err != nil {
+		return err
+	}
+
+	kdcKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	kdcTmpl := &x509.Certificate{
+		SerialNumber:       big.NewInt(2),
+		Subject:            pkix.Name{Organization: []string{"gsstest"}, CommonName: "krbtgt/" + k.realm},
+		NotBefore:          now.Add(-time.Hour),
+		NotAfter:           now.Add(7 * 24 * time.Hour),
+		KeyUsage:           x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageKeyAgreement,
+		UnknownExtKeyUsage: []asn1.ObjectIdentifier{oidPKINITKPKdc},
+		ExtraExtensions:    []pkix.Extension{{Id: oidSubjectAltName, Value: san}},
+	}
+	kdcDER, err := x509.CreateCertificate(rand.Reader, kdcTmpl, caCert, &kdcKey.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		name  string
+		block *pem.Block
+	}{
+		{pkinitCAFile, &pem.Block{Type: "CERTIFICATE", Bytes: caDER}},
+		{pkinitCertFile, &pem.Block{Type: "CERTIFICATE", Bytes: kdcDER}},
+		{pkinitKeyFile, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(kdcKey)}},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(k.dir, f.name), pem.EncodeToMemory(f.block), 0600); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pkinitSan returns a subjectAltName extension value holding the Kerberos principal
+// name@realmName as an id-pkinit-san otherName (RFC 4556 § 3.2.2).  The encoding/asn1 package
+// can't marshal GeneralString or implicitly tagged sequences, so those are built by hand.
+func pkinitSan(name, realmName string) ([]byte, error) {
+	type principalName struct {
+		NameType   int32           `asn1:"explicit,tag:0"`
+		NameString []asn1.RawValue `asn1:"explicit,tag:1"`
+	}
+	type krb5PrincipalName struct {
+		Realm         asn1.RawValue // [0] EXPLICIT, as RawValue fields are marshaled verbatim
+		PrincipalName principalName `asn1:"explicit,tag:1"`
+	}
+
+	generalString := func(s string) asn1.RawValue {
+		return asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagGeneralString, Bytes: []byte(s)}
+	}
+
+	realm, err := asn1.Marshal(generalString(realmName))
+	if err != nil {
+		return nil, err
+	}
+
+	pn := principalName{NameType: 2} // KRB5_NT_SRV_INST
+	for _, c := range strings.Split(name, "/") {
+		pn.NameString = append(pn.NameString, generalString(c))
+	}
+
+	value, err := asn1.Marshal(krb5PrincipalName{
+		Realm:         asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: realm},
+		PrincipalName: pn,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// OtherName ::= SEQUENCE { type-id OID, value [0] EXPLICIT ANY }, tagged [0] IMPLICIT in GeneralName
+	otherName, err := asn1.Marshal(struct {
+		TypeID asn1.ObjectIdentifier
+		Value  asn1.RawValue
+	}{
+		TypeID: oidPKINITSan,
+		Value:  asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: value},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var seq asn1.RawValue
+	if _, err = asn1.Unmarshal(otherName, &seq); err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal([]asn1.RawValue{{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: seq.Bytes}})
+}
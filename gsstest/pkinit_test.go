@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gsstest
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPKINITSan(t *testing.T) {
+	san, err := pkinitSan("krbtgt/EXAMPLE.COM", "EXAMPLE.COM")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// GeneralNames with one otherName: [0] { OID, [0] { KRB5PrincipalName } }
+	var names []asn1.RawValue
+	_, err = asn1.Unmarshal(san, &names)
+	if !assert.NoError(t, err) || !assert.Len(t, names, 1) {
+		return
+	}
+	assert.Equal(t, asn1.ClassContextSpecific, names[0].Class)
+	assert.Equal(t, 0, names[0].Tag)
+
+	var oid asn1.ObjectIdentifier
+	rest, err := asn1.Unmarshal(names[0].Bytes, &oid)
+	assert.NoError(t, err)
+	assert.True(t, oid.Equal(oidPKINITSan))
+
+	var value asn1.RawValue
+	_, err = asn1.Unmarshal(rest, &value)
+	assert.NoError(t, err)
+
+	type principalName struct {
+		NameType   int32           `asn1:"explicit,tag:0"`
+		NameString []asn1.RawValue `asn1:"explicit,tag:1"`
+	}
+	var princ struct {
+		Realm         asn1.RawValue
+		PrincipalName principalName `asn1:"explicit,tag:1"`
+	}
+	_, err = asn1.Unmarshal(value.Bytes, &princ)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var realm asn1.RawValue
+	_, err = asn1.Unmarshal(princ.Realm.Bytes, &realm)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, princ.Realm.Tag)
+	assert.Equal(t, asn1.TagGeneralString, realm.Tag)
+	assert.Equal(t, "EXAMPLE.COM", string(realm.Bytes))
+	assert.Equal(t, int32(2), princ.PrincipalName.NameType)
+	if assert.Len(t, princ.PrincipalName.NameString, 2) {
+		assert.Equal(t, "krbtgt", string(princ.PrincipalName.NameString[0].Bytes))
+		assert.Equal(t, "EXAMPLE.COM", string(princ.PrincipalName.NameString[1].Bytes))
+	}
+}
+
+func TestWritePKINITIdentity(t *testing.T) {
+	k := &KDC{realm: DefaultRealm, dir: t.TempDir()}
+	if !assert.NoError(t, k.writePKINITIdentity()) {
+		return
+	}
+
+	readCert := func(name string) *x509.Certificate {
+		data, err := os.ReadFile(filepath.Join(k.dir, name))
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		block, _ := pem.Decode(data)
+		if !assert.NotNil(t, block) {
+			return nil
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		assert.NoError(t, err)
+		return cert
+	}
+
+	ca := readCert(pkinitCAFile)
+	kdc := readCert(pkinitCertFile)
+	if ca == nil || kdc == nil {
+		return
+	}
+
+	assert.True(t, ca.IsCA)
+	assert.NoError(t, kdc.CheckSignatureFrom(ca))
+	if assert.Len(t, kdc.UnknownExtKeyUsage, 1) {
+		assert.True(t, kdc.UnknownExtKeyUsage[0].Equal(oidPKINITKPKdc))
+	}
+
+	var hasSan bool
+	for _, ext := range kdc.Extensions {
+		hasSan = hasSan || ext.Id.Equal(oidSubjectAltName)
+	}
+	assert.True(t, hasSan)
+
+	_, err := os.Stat(filepath.Join(k.dir, pkinitKeyFile))
+	assert.NoError(t, err)
+	assert.Equal(t, "FILE:"+filepath.Join(k.dir, pkinitCAFile), k.PKINITAnchors())
+}
@@ -0,0 +1,31 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gsstest
+
+import (
+	"errors"
+	"testing"
+)
+
+// StartT starts a KDC for the duration of a test.  The test is skipped if no KDC
+// implementation is installed, fails if the KDC cannot be started, and the KDC is
+// closed automatically when the test and its subtests complete.
+func StartT(t testing.TB, opts ...Option) *KDC {
+	t.Helper()
+
+	k, err := Start(opts...)
+	if errors.Is(err, ErrNoKDC) {
+		t.Skipf("skipping test: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("starting test KDC: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := k.Close(); err != nil {
+			t.Errorf("closing test KDC: %v", err)
+		}
+	})
+
+	return k
+}
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"net"
 	"runtime"
+	"strconv"
 	"strings"
 	"unsafe"
 
@@ -39,6 +40,39 @@ func oid2String(oid g.Oid) (string, error) {
 	return s.String(), nil
 }
 
+// Convert a dotted OID string to a Go OID
+func string2Oid(s string) (g.Oid, error) {
+	var objID asn1.ObjectIdentifier
+	for _, part := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("bad OID %q", s)
+		}
+		objID = append(objID, n)
+	}
+
+	der, err := asn1.Marshal(objID)
+	if err != nil {
+		return nil, fmt.Errorf("bad OID %q: %w", s, err)
+	}
+
+	// strip the tag and length
+	v, _, err := readDER(der)
+	if err != nil {
+		return nil, err
+	}
+	return v.body, nil
+}
+
+// mustString2Oid is string2Oid for OIDs defined by the package, which are known to be good
+func mustString2Oid(s string) g.Oid {
+	oid, err := string2Oid(s)
+	if err != nil {
+		panic(err)
+	}
+	return oid
+}
+
 // Convert from a C OID set to slice of OID objects
 func oidsFromGssOidSet(oidSet C.gss_OID_set) []g.Oid {
 	ret := make([]g.Oid, oidSet.count)
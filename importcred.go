@@ -0,0 +1,173 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+/*
+#include "gss.h"
+#include <stdint.h>
+#include <stdlib.h>
+
+// krb5 routines looked up by tenantcache.go
+extern int32_t (*__gogssapi_krb5_init_context)(void **ctx);
+extern void (*__gogssapi_krb5_free_context)(void *ctx);
+extern int32_t (*__gogssapi_krb5_cc_resolve)(void *ctx, const char *name, void **cc);
+extern int32_t (*__gogssapi_krb5_cc_close)(void *ctx, void *cc);
+
+// The function pointers below are set to the actual function pointers in the library
+// by the init function using symbolMap.Apply().  The krb5 handles are opaque pointers in
+// both MIT and Heimdal.
+int32_t (*__gogssapi_krb5_kt_resolve)(void *ctx, const char *name, void **kt) = NULL;
+int32_t (*__gogssapi_krb5_kt_close)(void *ctx, void *kt) = NULL;
+int32_t (*__gogssapi_krb5_parse_name)(void *ctx, const char *name, void **principal) = NULL;
+void (*__gogssapi_krb5_free_principal)(void *ctx, void *principal) = NULL;
+OM_uint32 (*__gogssapi_krb5_import_cred)(OM_uint32 *minor_status,
+	void *id,
+	void *keytab_principal,
+	void *keytab,
+	gss_cred_id_t *cred) = NULL;
+
+int _gogssapi_have_krb5_import_cred() {
+	return __gogssapi_krb5_import_cred != NULL &&
+		__gogssapi_krb5_init_context != NULL && __gogssapi_krb5_free_context != NULL &&
+		__gogssapi_krb5_cc_resolve != NULL && __gogssapi_krb5_cc_close != NULL &&
+		__gogssapi_krb5_kt_resolve != NULL && __gogssapi_krb5_kt_close != NULL &&
+		__gogssapi_krb5_parse_name != NULL && __gogssapi_krb5_free_principal != NULL;
+}
+
+// Resolve the cache, keytab and principal, any of which may be NULL, and import them as a
+// credential.  krb5 errors are returned in code; the library takes its own references to the
+// handles so they are released here.
+static OM_uint32 import_cred(OM_uint32 *minor_status, const char *ccname, const char *ktname, const char *principal, gss_cred_id_t *cred, int32_t *code) {
+	void *ctx = NULL;
+	void *cc = NULL;
+	void *kt = NULL;
+	void *princ = NULL;
+	OM_uint32 major = GSS_S_FAILURE;
+
+	*minor_status = 0;
+	*code = __gogssapi_krb5_init_context(&ctx);
+	if( *code != 0 ) {
+		return major;
+	}
+
+	if( ccname != NULL ) {
+		*code = __gogssapi_krb5_cc_resolve(ctx, ccname, &cc);
+	}
+	if( *code == 0 && ktname != NULL ) {
+		*code = __gogssapi_krb5_kt_resolve(ctx, ktname, &kt);
+	}
+	if( *code == 0 && principal != NULL ) {
+		*code = __gogssapi_krb5_parse_name(ctx, principal, &princ);
+	}
+	if( *code == 0 ) {
+		major = __gogssapi_krb5_import_cred(minor_status, cc, princ, kt, cred);
+	}
+
+	if( princ != NULL ) {
+		__gogssapi_krb5_free_principal(ctx, princ);
+	}
+	if( kt != NULL ) {
+		__gogssapi_krb5_kt_close(ctx, kt);
+	}
+	if( cc != NULL ) {
+		__gogssapi_krb5_cc_close(ctx, cc);
+	}
+	__gogssapi_krb5_free_context(ctx);
+	return major;
+}
+
+OM_uint32 _gogssapi_krb5_import_cred(OM_uint32 *minor_status, const char *ccname, const char *ktname, const char *principal, gss_cred_id_t *cred, int32_t *code) {
+	return _GOGSSAPI_LOCKED(import_cred(minor_status, ccname, ktname, principal, cred, code));
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"unsafe"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// Map optional symbols from the GSSAPI and krb5 libraries to the wrapper function pointers
+var importCredSymbols = symbolMap{
+	"krb5_kt_resolve":      &C.__gogssapi_krb5_kt_resolve,
+	"krb5_kt_close":        &C.__gogssapi_krb5_kt_close,
+	"krb5_parse_name":      &C.__gogssapi_krb5_parse_name,
+	"krb5_free_principal":  &C.__gogssapi_krb5_free_principal,
+	"gss_krb5_import_cred": &C.__gogssapi_krb5_import_cred,
+}
+
+func init() {
+	importCredSymbols.Apply()
+}
+
+func hasKrb5ImportCred() bool {
+	return C._gogssapi_have_krb5_import_cred() == 1
+}
+
+// ImportKrb5Credential builds a Kerberos credential from a credentials cache and a keytab using
+// gss_krb5_import_cred, without reference to KRB5CCNAME, KRB5_KTNAME or the credential store
+// extension.  This allows a particular cache or keytab to be used with Apple Kerberos and old
+// Heimdal releases, which lack the credential store extension.
+//
+// A credential made from just a cache can initiate contexts, and from just a keytab can accept
+// them; with both it can do either.  principal restricts the acceptor to that principal's keys
+// in the keytab, and may be empty to accept using any of them.  The names are krb5 cache and
+// keytab names, such as FILE:/tmp/krb5cc_1000.
+//
+// The method is not part of the go-gssapi Provider interface, so callers need a type assertion:
+//
+//	type krb5Importer interface {
+//		ImportKrb5Credential(ccache, keytab, principal string) (g.Credential, error)
+//	}
+//	if p, ok := provider.(krb5Importer); ok {
+//		cred, err := p.ImportKrb5Credential("FILE:/tmp/krb5cc_app", "", "")
+//	}
+func (provider) ImportKrb5Credential(ccache, keytab, principal string) (g.Credential, error) {
+	if !hasKrb5ImportCred() {
+		return nil, makeCustomStatus(C.GSS_S_UNAVAILABLE, errors.New("gss_krb5_import_cred is not available in this Kerberos library"))
+	}
+
+	var usage g.CredUsage
+	switch {
+	case ccache != "" && keytab != "":
+		usage = g.CredUsageInitiateAndAccept
+	case ccache != "":
+		usage = g.CredUsageInitiateOnly
+	case keytab != "":
+		usage = g.CredUsageAcceptOnly
+	default:
+		return nil, makeCustomStatus(C.GSS_S_NO_CRED, errors.New("a credentials cache or keytab is required"))
+	}
+
+	// allocated by C; released by *1
+	var cCCache, cKeytab, cPrincipal *C.char
+	for _, v := range []struct {
+		s string
+		p **C.char
+	}{{ccache, &cCCache}, {keytab, &cKeytab}, {principal, &cPrincipal}} {
+		if v.s != "" {
+			*v.p = C.CString(v.s)
+			// *1  release the names
+			defer C.free(unsafe.Pointer(*v.p))
+		}
+	}
+
+	var minor C.OM_uint32
+	var code C.int32_t
+	var cCredID C.gss_cred_id_t = C.GSS_C_NO_CREDENTIAL
+	major := C._gogssapi_krb5_import_cred(&minor, cCCache, cKeytab, cPrincipal, &cCredID, &code)
+	if code != 0 {
+		return nil, krb5Error(code)
+	}
+	if major != C.GSS_S_COMPLETE {
+		return nil, makeMechStatus(major, minor, g.GSS_MECH_KRB5)
+	}
+
+	return &Credential{
+		id:           cCredID,
+		usage:        usage,
+		isFromNoName: principal == "" && usage == g.CredUsageAcceptOnly,
+	}, nil
+}
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"path/filepath"
+	"testing"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+func TestImportKrb5Credential(t *testing.T) {
+	assert := NewAssert(t)
+	if !hasKrb5ImportCred() {
+		t.Skip("gss_krb5_import_cred is not available in this GSSAPI library")
+	}
+
+	// the default cache and keytab are empty so the credentials can only come from the imports
+	ta.useAsset(t, testNoCredCache|testNoKeytab)
+	dir := t.TempDir()
+	ccName := filepath.Join(dir, "ccache")
+	ktName := filepath.Join(dir, "keytab")
+	assert.NoErrorFatal(CopyFile(ta.ccfile, ccName))
+	assert.NoErrorFatal(CopyFile(ta.ktfileRack, ktName))
+
+	lib := ta.lib.(*provider)
+	_, err := lib.ImportKrb5Credential("", "", "")
+	assert.ErrorIs(err, g.ErrNoCred)
+
+	initCred, err := lib.ImportKrb5Credential("FILE:"+ccName, "", "")
+	assert.NoErrorFatal(err)
+	defer initCred.Release() //nolint:errcheck
+
+	info, err := initCred.Inquire()
+	assert.NoErrorFatal(err)
+	assert.Equal("robot@GOLANG-AUTH.IO", info.Name)
+
+	acceptCred, err := lib.ImportKrb5Credential("", "FILE:"+ktName, "rack/foo.golang-auth.io@GOLANG-AUTH.IO")
+	assert.NoErrorFatal(err)
+	defer acceptCred.Release() //nolint:errcheck
+
+	_, secCtxAcceptor, _ := establishContextsWith(t, "rack@foo.golang-auth.io", contextOptions{
+		initiator: []g.InitSecContextOption{g.WithInitiatorCredential(initCred), g.WithInitiatorFlags(g.ContextFlagMutual)},
+		acceptor:  []g.AcceptSecContextOption{g.WithAcceptorCredential(acceptCred)},
+	})
+
+	ctxInfo, err := secCtxAcceptor.Inquire()
+	assert.NoErrorFatal(err)
+	initiatorName, _, err := ctxInfo.InitiatorName.Display()
+	assert.NoError(err)
+	assert.Equal("robot@GOLANG-AUTH.IO", initiatorName)
+}
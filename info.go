@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+/*
+#define _GNU_SOURCE
+#include "gss.h"
+#include <dlfcn.h>
+
+// Returns the path of the shared object containing addr, or NULL
+const char *_gogssapi_library_file(void *addr) {
+	Dl_info info;
+	if( addr == NULL || dladdr(addr, &info) == 0 ) {
+		return NULL;
+	}
+	return info.dli_fname;
+}
+*/
+import "C"
+
+import (
+	"slices"
+)
+
+// Family identifies the GSSAPI implementation used by the provider
+type Family string
+
+const (
+	// MIT Kerberos
+	FamilyMIT Family = "MIT"
+	// Heimdal, apart from the forks below
+	FamilyHeimdal Family = "Heimdal"
+	// The Heimdal fork in the FreeBSD base system before FreeBSD 15
+	FamilyFreeBSD Family = "FreeBSD"
+	// The Heimdal fork in the MacOS GSS framework
+	FamilyApple Family = "Apple"
+)
+
+// Quirk identifies a workaround that the provider applies for a bug in the GSSAPI
+// implementation.  The README describes each of them.
+type Quirk string
+
+const (
+	// Credential.Add returns GSS_S_UNAVAILABLE because gss_add_cred is unusable
+	QuirkNoAddCred Quirk = "no-add-cred"
+	// Names of acceptor credentials acquired without a name cannot be displayed or compared
+	QuirkNoNameAcceptorCred Quirk = "no-name-acceptor-cred"
+	// The credential usage and expiry times from gss_inquire_cred are corrected
+	QuirkInquireCredUsage Quirk = "inquire-cred-usage"
+	// gss_inquire_context returns no mechanism, so Kerberos is assumed
+	QuirkInquireContextMech Quirk = "inquire-context-mech"
+)
+
+// ProviderInfo describes the GSSAPI implementation in use, for diagnostics
+type ProviderInfo struct {
+	Family Family
+	// Version is the release reported by a Heimdal library through heimdal_version.  MIT
+	// Kerberos has no equivalent, so Version is always empty for FamilyMIT.
+	Version string
+	// Library is the path of the shared object providing the GSSAPI functions, if known
+	Library string
+	// Symbols are the optional library symbols that were found, in order
+	Symbols      []string
+	Quirks       []Quirk
+	ThreadSafety ThreadSafety
+	// SerializedCalls reports whether calls into the library are being serialized
+	SerializedCalls bool
+	// ChannelBound reports whether the library signals the use of channel bindings
+	ChannelBound bool
+}
+
+// Info reports the GSSAPI implementation used by the provider, its optional features and
+// the workarounds that are active.  It is not part of the go-gssapi Provider interface, so
+// callers need a type assertion:
+//
+//	if p, ok := provider.(interface{ Info() gssapi.ProviderInfo }); ok {
+//		info := p.Info()
+//	}
+func (provider) Info() ProviderInfo {
+	info := ProviderInfo{
+		Family:       implementationFamily(),
+		Version:      libraryVersion(),
+		Symbols:      foundSymbols(),
+		ChannelBound: hasChannelBound(),
+	}
+
+	if cFile := C._gogssapi_library_file(librarySymbol("gss_display_name")); cFile != nil {
+		info.Library = C.GoString(cFile)
+	}
+
+	info.ThreadSafety = libraryThreadSafety()
+	info.SerializedCalls = SerializingCalls()
+
+	if isHeimdal() && !isHeimdalWorkingAddCred() {
+		info.Quirks = append(info.Quirks, QuirkNoAddCred)
+	}
+	if isHeimdalAfter7() {
+		info.Quirks = append(info.Quirks, QuirkNoNameAcceptorCred)
+	}
+	if isHeimdalFreeBSD() {
+		info.Quirks = append(info.Quirks, QuirkInquireCredUsage)
+	}
+	if isMacGssapi() {
+		info.Quirks = append(info.Quirks, QuirkInquireContextMech)
+	}
+
+	return info
+}
+
+func implementationFamily() Family {
+	switch {
+	case isMacGssapi():
+		return FamilyApple
+	case isHeimdalFreeBSD():
+		return FamilyFreeBSD
+	case isHeimdal():
+		return FamilyHeimdal
+	default:
+		return FamilyMIT
+	}
+}
+
+func libraryVersion() string {
+	// heimdal_version is a const char * variable
+	ptr := librarySymbol("heimdal_version")
+	if ptr == nil {
+		return ""
+	}
+
+	cVersion := *(**C.char)(ptr)
+	if cVersion == nil {
+		return ""
+	}
+	return C.GoString(cVersion)
+}
+
+// foundSymbols returns the optional symbols present in the library, sorted
+func foundSymbols() []string {
+	onceSymbols.Do(readSymbols)
+
+	symbolsMutex.RLock()
+	defer symbolsMutex.RUnlock()
+
+	syms := make([]string, 0, len(optionalSymbols))
+	for sym, ptr := range optionalSymbols {
+		if ptr != nil {
+			syms = append(syms, sym)
+		}
+	}
+	slices.Sort(syms)
+
+	return syms
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestProviderInfo(t *testing.T) {
+	assert := NewAssert(t)
+
+	info := ta.lib.(*provider).Info()
+
+	switch {
+	case isMacGssapi():
+		assert.Equal(FamilyApple, info.Family)
+	case isHeimdalFreeBSD():
+		assert.Equal(FamilyFreeBSD, info.Family)
+	case isHeimdal():
+		assert.Equal(FamilyHeimdal, info.Family)
+	default:
+		assert.Equal(FamilyMIT, info.Family)
+		assert.Empty(info.Version)
+	}
+
+	if info.Version != "" {
+		assert.True(strings.HasPrefix(info.Version, "Heimdal"))
+	}
+
+	assert.True(slices.IsSorted(info.Symbols))
+	for _, sym := range info.Symbols {
+		assert.True(hasSymbol(sym), sym)
+	}
+	assert.Equal(hasSymbol("gss_localname"), slices.Contains(info.Symbols, "gss_localname"))
+
+	if hasSymbol("krb5_is_thread_safe") {
+		assert.NotEqual(ThreadSafetyUnknown, info.ThreadSafety)
+	} else {
+		assert.Equal(ThreadSafetyUnknown, info.ThreadSafety)
+	}
+
+	assert.Equal(isHeimdal() && !isHeimdalWorkingAddCred(), slices.Contains(info.Quirks, QuirkNoAddCred))
+	assert.Equal(isHeimdalAfter7(), slices.Contains(info.Quirks, QuirkNoNameAcceptorCred))
+	assert.Equal(isHeimdalFreeBSD(), slices.Contains(info.Quirks, QuirkInquireCredUsage))
+	assert.Equal(isMacGssapi(), slices.Contains(info.Quirks, QuirkInquireContextMech))
+	assert.Equal(hasChannelBound(), info.ChannelBound)
+}
+
+func TestThreadSafetyString(t *testing.T) {
+	assert := NewAssert(t)
+
+	assert.Equal("thread safe", ThreadSafe.String())
+	assert.Equal("not thread safe", NotThreadSafe.String())
+	assert.Equal("unknown", ThreadSafetyUnknown.String())
+}
@@ -0,0 +1,296 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// ErrNoKeytabKey is returned by CheckAcceptorKeytab when the keytab has no key for the acceptor
+var ErrNoKeytabKey = errors.New("keytab does not contain a key for the acceptor")
+
+// ErrMalformedKeytab is returned when a keytab file cannot be decoded
+var ErrMalformedKeytab = errors.New("malformed keytab")
+
+// KeytabEntry is one key in a keytab.  The key itself is not retained.
+type KeytabEntry struct {
+	Realm     string
+	Principal PrincipalName
+	Timestamp time.Time // when the key was written to the keytab
+	Kvno      uint32
+	Enctype   int32
+}
+
+// String returns the principal name of the entry in the usual name/instance@REALM form
+func (e KeytabEntry) String() string {
+	return e.Principal.String() + "@" + e.Realm
+}
+
+// Keytab is the decoded contents of a Kerberos keytab file
+type Keytab struct {
+	Version int // 1 or 2
+	Entries []KeytabEntry
+}
+
+// Principals returns the distinct principal names in the keytab, in the order they first appear
+func (kt *Keytab) Principals() []string {
+	var ret []string
+	for _, e := range kt.Entries {
+		if s := e.String(); !slices.Contains(ret, s) {
+			ret = append(ret, s)
+		}
+	}
+
+	return ret
+}
+
+// ReadKeytab reads and decodes a keytab file.  The path may be prefixed with the FILE: or
+// WRFILE: keytab type; other keytab types cannot be read directly.
+func ReadKeytab(path string) (*Keytab, error) {
+	path, err := keytabPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseKeytab(data)
+}
+
+func keytabPath(name string) (string, error) {
+	kttype, path, found := strings.Cut(name, ":")
+	switch {
+	case !found || strings.HasPrefix(name, "/"):
+		return name, nil
+	case kttype == "FILE" || kttype == "WRFILE":
+		return path, nil
+	default:
+		return "", fmt.Errorf("keytab type %s is not supported", kttype)
+	}
+}
+
+// ParseKeytab decodes the contents of a keytab file in the format used by MIT and Heimdal:
+// version 0x0502, or the older 0x0501 which uses native byte order.
+func ParseKeytab(data []byte) (*Keytab, error) {
+	if len(data) < 2 || data[0] != 0x05 {
+		return nil, fmt.Errorf("%w: not a keytab file", ErrMalformedKeytab)
+	}
+
+	kt := &Keytab{}
+	var order binary.ByteOrder
+	switch data[1] {
+	default:
+		return nil, fmt.Errorf("%w: unsupported keytab version 0x05%02x", ErrMalformedKeytab, data[1])
+	case 0x01:
+		kt.Version = 1
+		order = binary.NativeEndian
+	case 0x02:
+		kt.Version = 2
+		order = binary.BigEndian
+	}
+
+	data = data[2:]
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("%w: truncated record length", ErrMalformedKeytab)
+		}
+		size := int32(order.Uint32(data))
+		data = data[4:]
+
+		// a negative size marks a hole left by a deleted entry
+		length := int64(size)
+		if size < 0 {
+			length = -length
+		}
+		if length > int64(len(data)) {
+			return nil, fmt.Errorf("%w: record length %d exceeds available data (%d bytes)", ErrMalformedKeytab, length, len(data))
+		}
+		record := data[:length]
+		data = data[length:]
+
+		if size <= 0 {
+			continue
+		}
+
+		entry, err := parseKeytabEntry(record, order, kt.Version)
+		if err != nil {
+			return nil, err
+		}
+		kt.Entries = append(kt.Entries, entry)
+	}
+
+	return kt, nil
+}
+
+// binReader reads the fields of an entry in a Kerberos binary file, remembering the first error
+type binReader struct {
+	b         []byte
+	order     binary.ByteOrder
+	malformed error // the error to report for truncated input
+	err       error
+}
+
+func (r *binReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.b) {
+		r.err = fmt.Errorf("%w: truncated entry", r.malformed)
+		return nil
+	}
+	ret := r.b[:n]
+	r.b = r.b[n:]
+
+	return ret
+}
+
+func (r *binReader) uint8() uint8 {
+	if b := r.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *binReader) uint16() uint16 {
+	if b := r.take(2); b != nil {
+		return r.order.Uint16(b)
+	}
+	return 0
+}
+
+func (r *binReader) uint32() uint32 {
+	if b := r.take(4); b != nil {
+		return r.order.Uint32(b)
+	}
+	return 0
+}
+
+// data16 reads an octet string with a 16 bit length
+func (r *binReader) data16() []byte {
+	return r.take(int(r.uint16()))
+}
+
+// data32 reads an octet string with a 32 bit length
+func (r *binReader) data32() []byte {
+	return r.take(int(r.uint32()))
+}
+
+func parseKeytabEntry(record []byte, order binary.ByteOrder, version int) (KeytabEntry, error) {
+	r := binReader{b: record, order: order, malformed: ErrMalformedKeytab}
+	entry := KeytabEntry{}
+
+	numComponents := int(r.uint16())
+	if version == 1 {
+		// version 1 counts the realm as a component
+		numComponents--
+	}
+	entry.Realm = string(r.data16())
+	for range max(numComponents, 0) {
+		entry.Principal.NameString = append(entry.Principal.NameString, string(r.data16()))
+	}
+	if version == 1 {
+		entry.Principal.NameType = 1 // KRB5_NT_PRINCIPAL
+	} else {
+		entry.Principal.NameType = int32(r.uint32())
+	}
+
+	entry.Timestamp = time.Unix(int64(r.uint32()), 0)
+	entry.Kvno = uint32(r.uint8())
+	entry.Enctype = int32(int16(r.uint16()))
+	_ = r.data16() // key contents
+	if r.err != nil {
+		return entry, r.err
+	}
+
+	// the 8 bit key version is extended by an optional 32 bit version at the end
+	if len(r.b) >= 4 {
+		if kvno := r.uint32(); kvno != 0 {
+			entry.Kvno = kvno
+		}
+	}
+
+	return entry, nil
+}
+
+// CheckAcceptorKeytab checks that the keytab named by the CredStoreServerKeytab option holds a
+// key for the acceptor name, so that configuration mistakes are reported before
+// AcquireCredentialFrom is called, or before the first initiator is rejected.
+// A nil name, which makes the acceptor use any key in the keytab, only requires the keytab to
+// contain a key.  Nil is returned if the options do not name a keytab.
+func CheckAcceptorKeytab(name g.GssName, opts ...g.CredStoreOption) error {
+	store := newCredStore()
+	for _, opt := range opts {
+		if err := opt(&store); err != nil {
+			return err
+		}
+	}
+
+	ktName, ok := store.GetOption(int(g.CredStoreServerKeytab))
+	if !ok {
+		return nil
+	}
+
+	kt, err := ReadKeytab(ktName)
+	if err != nil {
+		return err
+	}
+
+	if name == nil {
+		if len(kt.Entries) == 0 {
+			return fmt.Errorf("%w: keytab %s is empty", ErrNoKeytabKey, ktName)
+		}
+		return nil
+	}
+
+	principal, err := acceptorPrincipal(name)
+	if err != nil {
+		return err
+	}
+
+	if !slices.ContainsFunc(kt.Entries, func(e KeytabEntry) bool { return keytabEntryMatches(e, principal) }) {
+		return fmt.Errorf("%w: keytab %s has no key for %s (it holds keys for: %s)",
+			ErrNoKeytabKey, ktName, principal, strings.Join(kt.Principals(), ", "))
+	}
+
+	return nil
+}
+
+// acceptorPrincipal returns the Kerberos principal name for name
+func acceptorPrincipal(name g.GssName) (string, error) {
+	krbName, err := name.Canonicalize(g.GSS_MECH_KRB5)
+	if err != nil {
+		return "", err
+	}
+	defer krbName.Release() //nolint:errcheck
+
+	principal, _, err := krbName.Display()
+
+	return principal, err
+}
+
+// keytabEntryMatches compares a keytab entry with a principal name as displayed by the Kerberos
+// mechanism.  Host-based service names may not have a realm until the acceptor has
+// seen a ticket, in which case any realm matches.
+func keytabEntryMatches(e KeytabEntry, principal string) bool {
+	components, realm := principal, ""
+	if i := strings.LastIndex(principal, "@"); i >= 0 {
+		components, realm = principal[:i], principal[i+1:]
+	}
+
+	if components != e.Principal.String() {
+		return false
+	}
+
+	return realm == "" || realm == e.Realm
+}
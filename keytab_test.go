@@ -0,0 +1,210 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+func decodeTestVector(t *testing.T, b64 string) []byte {
+	data, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseKeytab(t *testing.T) {
+	assert := NewAssert(t)
+
+	kt, err := ParseKeytab(decodeTestVector(t, ktdataAll))
+	assert.NoErrorFatal(err)
+
+	assert.Equal(2, kt.Version)
+	assert.Len(kt.Entries, 5)
+	assert.Equal([]string{
+		"robot@GOLANG-AUTH.IO",
+		"rack/foo.golang-auth.io@GOLANG-AUTH.IO",
+		"ruin/bar.golang-auth.io@GOLANG-AUTH.IO",
+	}, kt.Principals())
+
+	e := kt.Entries[0]
+	assert.Equal("GOLANG-AUTH.IO", e.Realm)
+	assert.Equal(int32(1), e.Principal.NameType)
+	assert.Equal(uint32(1), e.Kvno)
+	assert.Equal(int32(EnctypeAes256CtsHmacSha196), e.Enctype)
+	assert.Equal(time.Unix(0x68e283ef, 0), e.Timestamp)
+
+	e = kt.Entries[2]
+	assert.Equal("rack/foo.golang-auth.io@GOLANG-AUTH.IO", e.String())
+	assert.Equal(int32(1), e.Principal.NameType)
+	assert.Equal(uint32(2), e.Kvno)
+	assert.Equal(int32(EnctypeAes128CtsHmacSha196), e.Enctype)
+}
+
+// mkTestKeytabEntry builds a version 2 keytab record
+func mkTestKeytabEntry(realm string, names []string, kvno8 uint8, kvno32 *uint32) []byte {
+	var b []byte
+	b = binary.BigEndian.AppendUint16(b, uint16(len(names)))
+	b = binary.BigEndian.AppendUint16(b, uint16(len(realm)))
+	b = append(b, realm...)
+	for _, n := range names {
+		b = binary.BigEndian.AppendUint16(b, uint16(len(n)))
+		b = append(b, n...)
+	}
+	b = binary.BigEndian.AppendUint32(b, 1)          // name type
+	b = binary.BigEndian.AppendUint32(b, 1700000000) // timestamp
+	b = append(b, kvno8)
+	b = binary.BigEndian.AppendUint16(b, EnctypeAes128CtsHmacSha196)
+	b = binary.BigEndian.AppendUint16(b, 16)
+	b = append(b, make([]byte, 16)...)
+	if kvno32 != nil {
+		b = binary.BigEndian.AppendUint32(b, *kvno32)
+	}
+
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(b))), b...)
+}
+
+func TestParseKeytabRecords(t *testing.T) {
+	assert := NewAssert(t)
+
+	kvno := uint32(300)
+	data := []byte{0x05, 0x02}
+	data = append(data, mkTestKeytabEntry("EXAMPLE.COM", []string{"a"}, 44, &kvno)...)
+	// hole left by a deleted entry
+	data = append(data, 0xff, 0xff, 0xff, 0xfc, 0, 0, 0, 0)
+	data = append(data, mkTestKeytabEntry("EXAMPLE.COM", []string{"b", "c"}, 7, nil)...)
+
+	kt, err := ParseKeytab(data)
+	assert.NoErrorFatal(err)
+	assert.Len(kt.Entries, 2)
+	assert.Equal(uint32(300), kt.Entries[0].Kvno)
+	assert.Equal(uint32(7), kt.Entries[1].Kvno)
+	assert.Equal("b/c@EXAMPLE.COM", kt.Entries[1].String())
+
+	// zero 32 bit kvno means use the 8 bit one
+	kvno = 0
+	kt, err = ParseKeytab(append([]byte{0x05, 0x02}, mkTestKeytabEntry("EXAMPLE.COM", []string{"a"}, 9, &kvno)...))
+	assert.NoErrorFatal(err)
+	assert.Equal(uint32(9), kt.Entries[0].Kvno)
+
+	// empty keytab
+	kt, err = ParseKeytab([]byte{0x05, 0x02})
+	assert.NoError(err)
+	assert.Empty(kt.Entries)
+}
+
+func TestParseKeytabV1(t *testing.T) {
+	assert := NewAssert(t)
+
+	order := binary.NativeEndian
+	var b []byte
+	b = order.AppendUint16(b, 2) // realm counts as a component
+	b = order.AppendUint16(b, 11)
+	b = append(b, "EXAMPLE.COM"...)
+	b = order.AppendUint16(b, 4)
+	b = append(b, "user"...)
+	b = order.AppendUint32(b, 1700000000)
+	b = append(b, 3)
+	b = order.AppendUint16(b, EnctypeDes3CbcSha1)
+	b = order.AppendUint16(b, 0)
+
+	data := append([]byte{0x05, 0x01}, order.AppendUint32(nil, uint32(len(b)))...)
+	kt, err := ParseKeytab(append(data, b...))
+	assert.NoErrorFatal(err)
+
+	assert.Equal(1, kt.Version)
+	assert.Len(kt.Entries, 1)
+	assert.Equal("user@EXAMPLE.COM", kt.Entries[0].String())
+	assert.Equal(uint32(3), kt.Entries[0].Kvno)
+	assert.Equal(int32(EnctypeDes3CbcSha1), kt.Entries[0].Enctype)
+}
+
+func TestParseKeytabMalformed(t *testing.T) {
+	good := decodeTestVector(t, ktdata1)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not-keytab", []byte("hello")},
+		{"version", []byte{0x05, 0x03}},
+		{"truncated-length", good[:4]},
+		{"truncated-record", good[:len(good)-4]},
+		{"short-record", []byte{0x05, 0x02, 0, 0, 0, 3, 0, 1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := NewAssert(t)
+
+			_, err := ParseKeytab(tt.data)
+			assert.ErrorIs(err, ErrMalformedKeytab)
+		})
+	}
+}
+
+func TestReadKeytab(t *testing.T) {
+	assert := NewAssert(t)
+
+	fn := filepath.Join(t.TempDir(), "test.keytab")
+	assert.NoErrorFatal(os.WriteFile(fn, decodeTestVector(t, ktdata1), 0600))
+
+	for _, name := range []string{fn, "FILE:" + fn, "WRFILE:" + fn} {
+		kt, err := ReadKeytab(name)
+		assert.NoError(err)
+		assert.Equal([]string{"rack/foo.golang-auth.io@GOLANG-AUTH.IO"}, kt.Principals())
+	}
+
+	_, err := ReadKeytab("MEMORY:foo")
+	assert.Error(err)
+	_, err = ReadKeytab(filepath.Join(t.TempDir(), "missing"))
+	assert.ErrorIs(err, os.ErrNotExist)
+}
+
+func TestKeytabEntryMatches(t *testing.T) {
+	assert := NewAssert(t)
+
+	e := KeytabEntry{Realm: "GOLANG-AUTH.IO", Principal: PrincipalName{NameString: []string{"rack", "foo.golang-auth.io"}}}
+	assert.True(keytabEntryMatches(e, "rack/foo.golang-auth.io@GOLANG-AUTH.IO"))
+	assert.True(keytabEntryMatches(e, "rack/foo.golang-auth.io@"))
+	assert.True(keytabEntryMatches(e, "rack/foo.golang-auth.io"))
+	assert.False(keytabEntryMatches(e, "rack/foo.golang-auth.io@OTHER.REALM"))
+	assert.False(keytabEntryMatches(e, "ruin/bar.golang-auth.io@GOLANG-AUTH.IO"))
+}
+
+func TestCheckAcceptorKeytab(t *testing.T) {
+	assert := NewAssert(t)
+
+	name, err := ta.lib.ImportName("rack@foo.golang-auth.io", g.GSS_NT_HOSTBASED_SERVICE)
+	assert.NoErrorFatal(err)
+	defer name.Release() //nolint:errcheck
+
+	err = CheckAcceptorKeytab(name, g.WithCredStoreServerKeytab("FILE:"+ta.ktfileRack))
+	assert.NoError(err)
+	err = CheckAcceptorKeytab(name, g.WithCredStoreServerKeytab(ta.ktfileAll))
+	assert.NoError(err)
+
+	err = CheckAcceptorKeytab(name, g.WithCredStoreServerKeytab("FILE:"+ta.ktfileRuin))
+	assert.ErrorIs(err, ErrNoKeytabKey)
+	assert.ErrorContains(err, "ruin/bar.golang-auth.io@GOLANG-AUTH.IO")
+
+	// any key will do for the default acceptor
+	err = CheckAcceptorKeytab(nil, g.WithCredStoreServerKeytab("FILE:"+ta.ktfileRuin))
+	assert.NoError(err)
+
+	// nothing to check
+	err = CheckAcceptorKeytab(name, g.WithCredStoreCCache("FILE:"+ta.ccfile))
+	assert.NoError(err)
+
+	err = CheckAcceptorKeytab(name, g.WithCredStoreServerKeytab("FILE:/no/such/file"))
+	assert.ErrorIs(err, os.ErrNotExist)
+}
@@ -0,0 +1,280 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// ErrNoKrbError is returned by DecodeKrbError when the token does not hold a Kerberos error
+var ErrNoKrbError = errors.New("token does not contain a Kerberos KRB-ERROR")
+
+// PrincipalName is a Kerberos principal name (RFC 4120 § 5.2.2)
+type PrincipalName struct {
+	NameType   int32
+	NameString []string
+}
+
+// String returns the name components separated by slashes, without a realm
+func (p PrincipalName) String() string {
+	return strings.Join(p.NameString, "/")
+}
+
+// PAData is a Kerberos pre-authentication data element (RFC 4120 § 5.2.7)
+type PAData struct {
+	Type  int32
+	Value []byte
+}
+
+// KrbError is a decoded Kerberos KRB-ERROR message (RFC 4120 § 5.9.1).  Kerberos GSSAPI
+// acceptors send one to the initiator in the output token when they reject a context, so
+// logging it tells the initiator precisely why it was rejected.
+//
+// KrbError implements the error interface.  Codes that correspond to one of the ErrKrb5*
+// values can be matched using errors.Is.
+type KrbError struct {
+	ErrorCode int32
+	CTime     time.Time // client time, if supplied
+	STime     time.Time // server time
+	CRealm    string
+	CName     PrincipalName
+	Realm     string // server realm
+	SName     PrincipalName
+	EText     string // additional text, if any
+	EData     []byte // additional data, if any; see PAData()
+}
+
+// Error implements error.Error()
+func (e *KrbError) Error() string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "KRB-ERROR %d", e.ErrorCode)
+	if err := e.Unwrap(); err != nil {
+		fmt.Fprintf(&s, " (%s)", err)
+	}
+	if e.EText != "" {
+		fmt.Fprintf(&s, ": %s", e.EText)
+	}
+
+	return s.String()
+}
+
+// Unwrap implements errors.Unwrap(), returning the ErrKrb5* value corresponding to
+// the error code, or nil if there is none.
+func (e *KrbError) Unwrap() error {
+	// protocol error codes are offsets into the krb5 com_err table
+	return krb5Errors[uint32(krb5ErrorTableBase+int64(e.ErrorCode))]
+}
+
+// PAData decodes the e-data field as METHOD-DATA, which the KDC sends with errors such as
+// KDC_ERR_PREAUTH_REQUIRED to list the acceptable pre-authentication types.
+func (e *KrbError) PAData() ([]PAData, error) {
+	if len(e.EData) == 0 {
+		return nil, nil
+	}
+
+	seq, err := derSequence(e.EData)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []PAData
+	for len(seq) > 0 {
+		v, rest, err := expectDER(seq, derClassUniversal, derTagSequence)
+		if err != nil {
+			return nil, err
+		}
+		seq = rest
+
+		fields, err := derFields(v.body)
+		if err != nil {
+			return nil, err
+		}
+
+		var pa PAData
+		if pa.Type, err = derInt32(fields[1]); err != nil {
+			return nil, err
+		}
+		if f, ok := fields[2]; ok {
+			if pa.Value, err = derOctetString(f); err != nil {
+				return nil, err
+			}
+		}
+		ret = append(ret, pa)
+	}
+
+	return ret, nil
+}
+
+// krb5 GSSAPI token identifier for KRB_ERROR tokens (RFC 1964 § 1.1)
+var krb5TokIDError = []byte{0x03, 0x00}
+
+// DecodeKrbError decodes the Kerberos KRB-ERROR carried by an error token, as returned by
+// Continue along with a failure status.  The token may be a Kerberos mechanism token with
+// the RFC 2743 framing, a SPNEGO negTokenResp wrapping one, or a bare KRB-ERROR.
+// ErrNoKrbError is returned for tokens that do not carry a KRB-ERROR.
+func DecodeKrbError(token []byte) (*KrbError, error) {
+	if len(token) == 0 {
+		return nil, ErrNoKrbError
+	}
+
+	switch token[0] {
+	default:
+		return nil, ErrNoKrbError
+	case tokenFramingTag:
+		oid, inner, err := splitFramedToken(token)
+		if err != nil {
+			return nil, err
+		}
+		if mech, err := g.MechFromOid(oid); err != nil || mech != g.GSS_MECH_KRB5 {
+			return nil, ErrNoKrbError
+		}
+		if len(inner) < 2 || !slices.Equal(inner[:2], krb5TokIDError) {
+			return nil, ErrNoKrbError
+		}
+		return parseKrbError(inner[2:])
+	case 0xa1: // SPNEGO negTokenResp
+		responseToken, err := spnegoResponseToken(token)
+		if err != nil {
+			return nil, err
+		}
+		return DecodeKrbError(responseToken)
+	case 0x7e: // [APPLICATION 30]
+		return parseKrbError(token)
+	}
+}
+
+// spnegoResponseToken extracts the responseToken from a SPNEGO negTokenResp (RFC 4178 § 4.2.2).
+// It returns nil if there is no responseToken.
+func spnegoResponseToken(token []byte) ([]byte, error) {
+	v, _, err := expectDER(token, derClassContext, 1)
+	if err != nil {
+		return nil, err
+	}
+	seq, err := derSequence(v.body)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := derFields(seq)
+	if err != nil {
+		return nil, err
+	}
+
+	f, ok := fields[2]
+	if !ok {
+		return nil, nil
+	}
+
+	return derOctetString(f)
+}
+
+func parseKrbError(b []byte) (*KrbError, error) {
+	app, rest, err := expectDER(b, derClassApplication, 30)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%w: trailing data after KRB-ERROR", ErrMalformedToken)
+	}
+
+	seq, err := derSequence(app.body)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := derFields(seq)
+	if err != nil {
+		return nil, err
+	}
+
+	msgType, err := derInt32(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	if msgType != 30 {
+		return nil, fmt.Errorf("%w: KRB-ERROR has message type %d", ErrMalformedToken, msgType)
+	}
+
+	ret := &KrbError{}
+	if ret.ErrorCode, err = derInt32(fields[6]); err != nil {
+		return nil, err
+	}
+	if ret.STime, err = derTime(fields[4]); err != nil {
+		return nil, err
+	}
+	if ret.Realm, err = derKerberosString(fields[9]); err != nil {
+		return nil, err
+	}
+	if ret.SName, err = parsePrincipalName(fields[10]); err != nil {
+		return nil, err
+	}
+
+	// optional fields
+	if f, ok := fields[2]; ok {
+		if ret.CTime, err = derTime(f); err != nil {
+			return nil, err
+		}
+	}
+	if f, ok := fields[7]; ok {
+		if ret.CRealm, err = derKerberosString(f); err != nil {
+			return nil, err
+		}
+	}
+	if f, ok := fields[8]; ok {
+		if ret.CName, err = parsePrincipalName(f); err != nil {
+			return nil, err
+		}
+	}
+	if f, ok := fields[11]; ok {
+		if ret.EText, err = derKerberosString(f); err != nil {
+			return nil, err
+		}
+	}
+	if f, ok := fields[12]; ok {
+		if ret.EData, err = derOctetString(f); err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
+
+func parsePrincipalName(b []byte) (PrincipalName, error) {
+	ret := PrincipalName{}
+
+	seq, err := derSequence(b)
+	if err != nil {
+		return ret, err
+	}
+	fields, err := derFields(seq)
+	if err != nil {
+		return ret, err
+	}
+
+	if ret.NameType, err = derInt32(fields[0]); err != nil {
+		return ret, err
+	}
+
+	names, err := derSequence(fields[1])
+	if err != nil {
+		return ret, err
+	}
+	for len(names) > 0 {
+		v, rest, err := readDER(names)
+		if err != nil {
+			return ret, err
+		}
+		s, err := derKerberosString(v.full)
+		if err != nil {
+			return ret, err
+		}
+		ret.NameString = append(ret.NameString, s)
+		names = rest
+	}
+
+	return ret, nil
+}
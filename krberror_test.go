@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"encoding/asn1"
+	"testing"
+	"time"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// DER builders for the token tests
+func derTLV(tag byte, parts ...[]byte) []byte {
+	var body []byte
+	for _, p := range parts {
+		body = append(body, p...)
+	}
+
+	var hdr []byte
+	switch l := len(body); {
+	case l < 0x80:
+		hdr = []byte{tag, byte(l)}
+	case l < 0x100:
+		hdr = []byte{tag, 0x81, byte(l)}
+	default:
+		hdr = []byte{tag, 0x82, byte(l >> 8), byte(l)}
+	}
+
+	return append(hdr, body...)
+}
+
+func derCtx(n int, parts ...[]byte) []byte {
+	return derTLV(0xa0+byte(n), parts...)
+}
+
+func derSeq(parts ...[]byte) []byte {
+	return derTLV(0x30, parts...)
+}
+
+func derInt(i int) []byte {
+	b, err := asn1.Marshal(i)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func derGenStr(s string) []byte {
+	return derTLV(0x1b, []byte(s))
+}
+
+func derOctets(b []byte) []byte {
+	return derTLV(0x04, b)
+}
+
+func derGenTime(t time.Time) []byte {
+	return derTLV(0x18, []byte(t.UTC().Format("20060102150405Z")))
+}
+
+func derPrincipal(nameType int, names ...string) []byte {
+	var strs [][]byte
+	for _, n := range names {
+		strs = append(strs, derGenStr(n))
+	}
+	return derSeq(derCtx(0, derInt(nameType)), derCtx(1, derSeq(strs...)))
+}
+
+func derFramed(oid g.Oid, inner ...[]byte) []byte {
+	return derTLV(0x60, append([][]byte{derTLV(0x06, oid)}, inner...)...)
+}
+
+var testSTime = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func mkTestKrbError(code int, eData []byte) []byte {
+	fields := [][]byte{
+		derCtx(0, derInt(5)),
+		derCtx(1, derInt(30)),
+		derCtx(4, derGenTime(testSTime)),
+		derCtx(5, derInt(1234)),
+		derCtx(6, derInt(code)),
+		derCtx(7, derGenStr("CLIENT.REALM")),
+		derCtx(8, derPrincipal(1, "robot")),
+		derCtx(9, derGenStr("GOLANG-AUTH.IO")),
+		derCtx(10, derPrincipal(3, "rack", "foo.golang-auth.io")),
+		derCtx(11, derGenStr("something went wrong")),
+	}
+	if eData != nil {
+		fields = append(fields, derCtx(12, derOctets(eData)))
+	}
+
+	return derTLV(0x7e, derSeq(fields...))
+}
+
+func TestDecodeKrbError(t *testing.T) {
+	assert := NewAssert(t)
+
+	raw := mkTestKrbError(37, nil)
+
+	tests := []struct {
+		name  string
+		token []byte
+	}{
+		{"bare", raw},
+		{"framed", derFramed(g.GSS_MECH_KRB5.Oid(), []byte{0x03, 0x00}, raw)},
+		{"spnego", derCtx(1, derSeq(derCtx(0, []byte{0x0a, 0x01, 0x02}), derCtx(2, derOctets(derFramed(g.GSS_MECH_KRB5.Oid(), []byte{0x03, 0x00}, raw)))))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := NewAssert(t)
+
+			krbErr, err := DecodeKrbError(tt.token)
+			assert.NoErrorFatal(err)
+
+			assert.Equal(int32(37), krbErr.ErrorCode)
+			assert.Equal(testSTime, krbErr.STime.UTC())
+			assert.True(krbErr.CTime.IsZero())
+			assert.Equal("CLIENT.REALM", krbErr.CRealm)
+			assert.Equal("robot", krbErr.CName.String())
+			assert.Equal("GOLANG-AUTH.IO", krbErr.Realm)
+			assert.Equal(int32(3), krbErr.SName.NameType)
+			assert.Equal([]string{"rack", "foo.golang-auth.io"}, krbErr.SName.NameString)
+			assert.Equal("rack/foo.golang-auth.io", krbErr.SName.String())
+			assert.Equal("something went wrong", krbErr.EText)
+			assert.Empty(krbErr.EData)
+
+			assert.ErrorIs(krbErr, ErrKrb5ClockSkew)
+			assert.Contains(krbErr.Error(), "KRB-ERROR 37")
+			assert.Contains(krbErr.Error(), "something went wrong")
+		})
+	}
+
+	// no error token
+	_, err := DecodeKrbError(nil)
+	assert.ErrorIs(err, ErrNoKrbError)
+	_, err = DecodeKrbError([]byte{0x30, 0x00})
+	assert.ErrorIs(err, ErrNoKrbError)
+
+	// AP-REQ token rather than an error
+	_, err = DecodeKrbError(derFramed(g.GSS_MECH_KRB5.Oid(), []byte{0x01, 0x00}, derTLV(0x6e)))
+	assert.ErrorIs(err, ErrNoKrbError)
+
+	// different mech
+	_, err = DecodeKrbError(derFramed(g.GSS_MECH_SPNEGO.Oid(), raw))
+	assert.ErrorIs(err, ErrNoKrbError)
+
+	// truncated
+	_, err = DecodeKrbError(raw[:len(raw)-4])
+	assert.ErrorIs(err, ErrMalformedToken)
+
+	// wrong message type
+	bad := derTLV(0x7e, derSeq(derCtx(0, derInt(5)), derCtx(1, derInt(14))))
+	_, err = DecodeKrbError(bad)
+	assert.ErrorIs(err, ErrMalformedToken)
+}
+
+func TestKrbErrorPAData(t *testing.T) {
+	assert := NewAssert(t)
+
+	methodData := derSeq(
+		derSeq(derCtx(1, derInt(2)), derCtx(2, derOctets(nil))),
+		derSeq(derCtx(1, derInt(19)), derCtx(2, derOctets([]byte{0x01, 0x02}))),
+	)
+
+	krbErr, err := DecodeKrbError(mkTestKrbError(25, methodData))
+	assert.NoErrorFatal(err)
+	assert.Equal(methodData, krbErr.EData)
+	assert.Nil(krbErr.Unwrap())
+
+	pa, err := krbErr.PAData()
+	assert.NoErrorFatal(err)
+	assert.Equal([]PAData{{Type: 2, Value: []byte{}}, {Type: 19, Value: []byte{0x01, 0x02}}}, pa)
+
+	krbErr.EData = []byte{0x04, 0x00}
+	_, err = krbErr.PAData()
+	assert.ErrorIs(err, ErrMalformedToken)
+
+	krbErr.EData = nil
+	pa, err = krbErr.PAData()
+	assert.NoError(err)
+	assert.Nil(pa)
+}
@@ -0,0 +1,173 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+/*
+#include "gss.h"
+#include <stdint.h>
+
+// The lucid context structures from gssapi_krb5.h, which MIT, Heimdal and Apple share.  They
+// are repeated here so that there is no build dependency on the Kerberos mechanism headers.
+typedef struct {
+	OM_uint32 type;
+	OM_uint32 length;
+	void *data;
+} _gogssapi_lucid_key;
+
+typedef struct {
+	OM_uint32 sign_alg;
+	OM_uint32 seal_alg;
+	_gogssapi_lucid_key ctx_key;
+} _gogssapi_rfc1964_keydata;
+
+typedef struct {
+	OM_uint32 have_acceptor_subkey;
+	_gogssapi_lucid_key ctx_key;
+	_gogssapi_lucid_key acceptor_subkey;
+} _gogssapi_cfx_keydata;
+
+typedef struct {
+	OM_uint32 version;
+	OM_uint32 initiate;
+	OM_uint32 endtime;
+	uint64_t send_seq;
+	uint64_t recv_seq;
+	OM_uint32 protocol;
+	_gogssapi_rfc1964_keydata rfc1964_kd;
+	_gogssapi_cfx_keydata cfx_kd;
+} _gogssapi_lucid_context_v1;
+
+// The function pointers are set to the library functions by the init function using symbolMap.Apply()
+OM_uint32 (*__gogssapi_krb5_export_lucid_sec_context)(OM_uint32 *minor_status,
+	gss_ctx_id_t *context_handle,
+	OM_uint32 version,
+	void **kctx) = NULL;
+
+OM_uint32 (*__gogssapi_krb5_free_lucid_sec_context)(OM_uint32 *minor_status, void *kctx) = NULL;
+
+OM_uint32 _gogssapi_krb5_export_lucid_sec_context(OM_uint32 *minor_status, gss_ctx_id_t *context_handle, OM_uint32 version, void **kctx) {
+	if( __gogssapi_krb5_export_lucid_sec_context == NULL || __gogssapi_krb5_free_lucid_sec_context == NULL ) {
+		*minor_status = 0;
+		return GSS_S_UNAVAILABLE;
+	}
+	return _GOGSSAPI_LOCKED(__gogssapi_krb5_export_lucid_sec_context(minor_status, context_handle, version, kctx));
+}
+
+OM_uint32 _gogssapi_krb5_free_lucid_sec_context(OM_uint32 *minor_status, void *kctx) {
+	return _GOGSSAPI_LOCKED(__gogssapi_krb5_free_lucid_sec_context(minor_status, kctx));
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// Map optional symbols from the GSSAPI library to the wrapper function pointers
+var lucidSymbols = symbolMap{
+	"gss_krb5_export_lucid_sec_context": &C.__gogssapi_krb5_export_lucid_sec_context,
+	"gss_krb5_free_lucid_sec_context":   &C.__gogssapi_krb5_free_lucid_sec_context,
+}
+
+func init() {
+	lucidSymbols.Apply()
+}
+
+// LucidProtocol is the Kerberos GSSAPI token format used by a lucid context
+type LucidProtocol uint32
+
+const (
+	LucidProtocolRFC1964 LucidProtocol = 0 // DES and DES3 tokens from RFC 1964
+	LucidProtocolRFC4121 LucidProtocol = 1 // CFX tokens from RFC 4121
+)
+
+// LucidKey is a Kerberos key from a lucid context
+type LucidKey struct {
+	Enctype int32
+	Key     []byte
+}
+
+// LucidContext is the state of a Kerberos context needed to protect messages outside of the
+// GSSAPI library, for example by RPCSEC_GSS in a kernel or offload engine.  The keys are
+// secret and should be cleared by the caller when they are no longer needed.
+type LucidContext struct {
+	Version  uint32
+	Initiate bool      // true if the context was exported by the initiator
+	Endtime  time.Time // the expiry of the context
+	SendSeq  uint64    // the sequence number of the next message to be sent
+	RecvSeq  uint64    // the sequence number expected in the next message from the peer
+	Protocol LucidProtocol
+
+	// Used with LucidProtocolRFC1964
+	SignAlg uint32
+	SealAlg uint32
+
+	// The context key: the initiator subkey or ticket session key for LucidProtocolRFC4121, or
+	// the only key for LucidProtocolRFC1964
+	ContextKey LucidKey
+
+	// Used with LucidProtocolRFC4121 if the acceptor asserted a subkey
+	HaveAcceptorSubkey bool
+	AcceptorSubkey     LucidKey
+}
+
+// ExportLucid exports a fully established Kerberos context in version 1 lucid form using
+// gss_krb5_export_lucid_sec_context, which is available in MIT, Heimdal and Apple Kerberos.
+// As with Export, the context is no longer valid afterwards and should not be used except to
+// call Delete.  GSS_S_UNAVAILABLE is returned if the library does not support lucid contexts.
+func (c *SecContext) ExportLucid() (*LucidContext, error) {
+	var cMinor C.OM_uint32
+	var cLucid unsafe.Pointer // allocated by GSSAPI; released by *1
+	cMajor := C._gogssapi_krb5_export_lucid_sec_context(&cMinor, &c.id, 1, &cLucid)
+	if cMajor != C.GSS_S_COMPLETE {
+		return nil, makeMechStatus(cMajor, cMinor, g.GSS_MECH_KRB5)
+	}
+
+	// At this point the original security context has been deallocated and is no
+	// longer valid
+
+	// *1  Release the lucid context
+	defer C._gogssapi_krb5_free_lucid_sec_context(&cMinor, cLucid)
+
+	v1 := (*C._gogssapi_lucid_context_v1)(cLucid)
+	if v1.version != 1 {
+		return nil, fmt.Errorf("unexpected lucid context version %d: %w", v1.version, g.ErrFailure)
+	}
+
+	ret := &LucidContext{
+		Version:  uint32(v1.version),
+		Initiate: v1.initiate != 0,
+		Endtime:  time.Unix(int64(v1.endtime), 0),
+		SendSeq:  uint64(v1.send_seq),
+		RecvSeq:  uint64(v1.recv_seq),
+		Protocol: LucidProtocol(v1.protocol),
+	}
+
+	switch ret.Protocol {
+	case LucidProtocolRFC1964:
+		ret.SignAlg = uint32(v1.rfc1964_kd.sign_alg)
+		ret.SealAlg = uint32(v1.rfc1964_kd.seal_alg)
+		ret.ContextKey = lucidKeyFromC(&v1.rfc1964_kd.ctx_key)
+	case LucidProtocolRFC4121:
+		ret.ContextKey = lucidKeyFromC(&v1.cfx_kd.ctx_key)
+		ret.HaveAcceptorSubkey = v1.cfx_kd.have_acceptor_subkey != 0
+		if ret.HaveAcceptorSubkey {
+			ret.AcceptorSubkey = lucidKeyFromC(&v1.cfx_kd.acceptor_subkey)
+		}
+	default:
+		return nil, fmt.Errorf("unknown lucid context protocol %d: %w", ret.Protocol, g.ErrFailure)
+	}
+
+	return ret, nil
+}
+
+func lucidKeyFromC(key *C._gogssapi_lucid_key) LucidKey {
+	return LucidKey{
+		Enctype: int32(key._type),
+		Key:     C.GoBytes(key.data, C.int(key.length)),
+	}
+}
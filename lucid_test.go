@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"testing"
+	"time"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+func TestExportLucid(t *testing.T) {
+	assert := NewAssert(t)
+	if !hasSymbol("gss_krb5_export_lucid_sec_context") {
+		t.Skip("gss_krb5_export_lucid_sec_context is not available in this GSSAPI library")
+	}
+
+	initiator, acceptor := establishContexts(t, g.ContextFlagMutual|g.ContextFlagSequence)
+
+	// exchange a message in each direction so that the sequence numbers move on
+	msg := []byte("Hello GSSAPI")
+	wrapped, _, err := initiator.Wrap(msg, true, 0)
+	assert.NoErrorFatal(err)
+	_, _, _, err = acceptor.Unwrap(wrapped)
+	assert.NoErrorFatal(err)
+	wrapped, _, err = acceptor.Wrap(msg, true, 0)
+	assert.NoErrorFatal(err)
+	_, _, _, err = initiator.Unwrap(wrapped)
+	assert.NoErrorFatal(err)
+
+	var sessionKey *ContextKey
+	if hasSymbol("gss_inquire_sec_context_by_oid") {
+		key, err := acceptor.SessionKey()
+		assert.NoErrorFatal(err)
+		sessionKey = &key
+	}
+
+	initLucid, err := initiator.ExportLucid()
+	assert.NoErrorFatal(err)
+	assert.Nil(initiator.id)
+
+	accLucid, err := acceptor.ExportLucid()
+	assert.NoErrorFatal(err)
+	assert.Nil(acceptor.id)
+
+	assert.Equal(uint32(1), initLucid.Version)
+	assert.True(initLucid.Initiate)
+	assert.False(accLucid.Initiate)
+	assert.True(initLucid.Endtime.After(time.Now()))
+	assert.WithinDuration(initLucid.Endtime, accLucid.Endtime, time.Second)
+
+	assert.Equal(initLucid.SendSeq, accLucid.RecvSeq)
+	assert.Equal(accLucid.SendSeq, initLucid.RecvSeq)
+
+	assert.Equal(initLucid.Protocol, accLucid.Protocol)
+	assert.Equal(initLucid.ContextKey, accLucid.ContextKey)
+	assert.NotEmpty(initLucid.ContextKey.Key)
+	assert.Equal(initLucid.HaveAcceptorSubkey, accLucid.HaveAcceptorSubkey)
+	assert.Equal(initLucid.AcceptorSubkey, accLucid.AcceptorSubkey)
+
+	if sessionKey != nil && initLucid.Protocol == LucidProtocolRFC4121 {
+		want := initLucid.ContextKey
+		if initLucid.HaveAcceptorSubkey {
+			want = initLucid.AcceptorSubkey
+		}
+		assert.Equal(want.Key, sessionKey.Key)
+		if sessionKey.Enctype != 0 {
+			assert.Equal(want.Enctype, sessionKey.Enctype)
+		}
+	}
+}
@@ -3,8 +3,6 @@ package gssapi
 // SPDX-License-Identifier: Apache-2.0
 
 import (
-	"errors"
-
 	g "github.com/golang-auth/go-gssapi/v3"
 )
 
@@ -28,16 +26,11 @@ func (p *provider) IndicateMechs() ([]g.GssMech, error) {
 	mechOids := oidsFromGssOidSet(cMechSet)
 
 	for _, oid := range mechOids {
-		mech, err := g.MechFromOid(oid)
-		switch {
-		default:
-			ret = append(ret, mech)
-		case errors.Is(err, g.ErrBadMech):
-			// warn
-			continue
-		case err != nil:
+		mech, err := mechFromOid(oid)
+		if err != nil {
 			return nil, err
 		}
+		ret = append(ret, mech)
 	}
 	return ret, nil
 }
@@ -8,8 +8,8 @@ package gssapi
 import "C"
 
 import (
-	"errors"
 	"fmt"
+	"strings"
 
 	g "github.com/golang-auth/go-gssapi/v3"
 )
@@ -20,11 +20,32 @@ type GssName struct {
 	isFromNoName bool
 }
 
+// AnonymousPrincipal is the well-known Kerberos principal of an anonymous initiator (RFC 8062).
+// Display returns it for names of type GSS_NT_ANONYMOUS, and ImportName accepts it, or a
+// realm-specific anonymous principal such as WELLKNOWN/ANONYMOUS@EXAMPLE.COM, with that type.
+const AnonymousPrincipal = "WELLKNOWN/ANONYMOUS@WELLKNOWN:ANONYMOUS"
+
+// the name component shared by the fully and realm-specific anonymous principals
+const anonymousPrefix = "WELLKNOWN/ANONYMOUS@"
+
 func nameFromGssInternal(name C.gss_name_t) *GssName {
 	return &GssName{name, false}
 }
 
 func (provider) ImportName(name string, nameType g.GssNameType) (g.GssName, error) {
+	// The anonymous name has no text of its own.  Accept the anonymous principals as well, as
+	// that is what Display returns; a realm-specific one is imported as a Kerberos principal.
+	if nameType == g.GSS_NT_ANONYMOUS {
+		switch {
+		case name == "" || name == AnonymousPrincipal:
+			name = ""
+		case strings.HasPrefix(name, anonymousPrefix) && len(name) > len(anonymousPrefix):
+			nameType = g.GSS_KRB5_NT_PRINCIPAL_NAME
+		default:
+			return nil, fmt.Errorf("anonymous name %q: %w", name, g.ErrBadName)
+		}
+	}
+
 	cNameOid, pinner := oid2Coid(nameType.Oid(), nil)
 	cNameBuf, pinner := bytesToCBuffer([]byte(name), pinner)
 	defer pinner.Unpin()
@@ -64,20 +85,15 @@ func (provider) InquireNamesForMech(mech g.GssMech) ([]g.GssNameType, error) {
 	seen := make(map[string]bool)
 
 	for _, oid := range nameTypeOids {
-		nt, err := g.NameTypeFromOid(oid)
-		switch {
-		default:
-			ntStr := nt.String()
-			if _, ok := seen[ntStr]; !ok {
-				ret = append(ret, nt)
-				seen[nt.String()] = true
-			}
-		case errors.Is(err, g.ErrBadNameType):
-			// warn
-			continue
-		case err != nil:
+		nt, err := nameTypeFromOid(oid)
+		if err != nil {
 			return nil, err
 		}
+		ntStr := nt.String()
+		if _, ok := seen[ntStr]; !ok {
+			ret = append(ret, nt)
+			seen[nt.String()] = true
+		}
 	}
 
 	return ret, nil
@@ -124,12 +140,39 @@ func (n *GssName) Display() (string, g.GssNameType, error) {
 	name := C.GoBytes(cOutputBuf.value, C.int(cOutputBuf.length))
 
 	oid := oidFromGssOid(cOutType)
-	nameType, err := g.NameTypeFromOid(oid)
+	nameType, err := nameTypeFromOid(oid)
 	if err != nil {
 		return "", g.GSS_NO_OID, err
 	}
 
-	return string(name), nameType, nil
+	displayName, nameType := normalizeAnonymous(string(name), nameType)
+	return displayName, nameType, nil
+}
+
+// normalizeAnonymous presents anonymous names consistently.  Names imported as GSS_NT_ANONYMOUS
+// display as an empty string on some libraries, and Heimdal reports the anonymous principal as an
+// ordinary Kerberos principal.
+func normalizeAnonymous(name string, nameType g.GssNameType) (string, g.GssNameType) {
+	switch {
+	case nameType == g.GSS_NT_ANONYMOUS && name == "":
+		return AnonymousPrincipal, nameType
+	case strings.HasPrefix(name, anonymousPrefix):
+		return name, g.GSS_NT_ANONYMOUS
+	}
+
+	return name, nameType
+}
+
+// IsAnonymous reports whether the name is the anonymous principal, as used by initiators
+// requesting GSS_C_ANON_FLAG.  Realm-specific anonymous names such as
+// WELLKNOWN/ANONYMOUS@EXAMPLE.COM are anonymous too.
+func (n *GssName) IsAnonymous() (bool, error) {
+	_, nameType, err := n.Display()
+	if err != nil {
+		return false, err
+	}
+
+	return nameType == g.GSS_NT_ANONYMOUS, nil
 }
 
 func (n *GssName) Release() error {
@@ -157,16 +200,11 @@ func (n *GssName) InquireMechs() ([]g.GssMech, error) {
 	mechOids := oidsFromGssOidSet(cMechSet)
 
 	for _, oid := range mechOids {
-		mech, err := g.MechFromOid(oid)
-		switch {
-		default:
-			ret = append(ret, mech)
-		case errors.Is(err, g.ErrBadMech):
-			// warn
-			continue
-		case err != nil:
+		mech, err := mechFromOid(oid)
+		if err != nil {
 			return nil, err
 		}
+		ret = append(ret, mech)
 	}
 
 	return ret, nil
@@ -16,7 +16,7 @@ OM_uint32 (_gogssapi_localname)(OM_uint32 *minor, const gss_name_t name, gss_OID
 		*minor = 0;
 		return GSS_S_UNAVAILABLE;
 	}
-	return __gogssapi_localname(minor, name, mech, output);
+	return _GOGSSAPI_LOCKED(__gogssapi_localname(minor, name, mech, output));
 }
 
 OM_uint32 (*__gogssapi_inquire_name)(OM_uint32 *, const gss_name_t, int *, gss_OID *, gss_buffer_set_t *) = NULL;
@@ -27,7 +27,7 @@ OM_uint32 (_gogssapi_inquire_name)(OM_uint32 *minor, const gss_name_t name, int
 		*minor = 0;
 		return GSS_S_UNAVAILABLE;
 	}
-	return __gogssapi_inquire_name(minor, name, name_is_MN, MN_mech, attrs);
+	return _GOGSSAPI_LOCKED(__gogssapi_inquire_name(minor, name, name_is_MN, MN_mech, attrs));
 }
 */
 import "C"
@@ -87,7 +87,7 @@ func (n *GssName) Inquire() (g.InquireNameInfo, error) {
 	ret.IsMechName = cNameIsMN == 1
 	if ret.IsMechName {
 		oid := oidFromGssOid(cMech)
-		nt, err := g.MechFromOid(oid)
+		nt, err := mechFromOid(oid)
 		if err != nil {
 			return ret, err
 		}
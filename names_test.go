@@ -104,6 +104,82 @@ func TestDisplayName(t *testing.T) {
 	assert.ErrorIs(err, g.ErrBadName)
 }
 
+func TestDisplayAnonymousName(t *testing.T) {
+	assert := NewAssert(t)
+
+	_, err := ta.lib.ImportName("someone", g.GSS_NT_ANONYMOUS)
+	assert.ErrorIs(err, g.ErrBadName)
+
+	for _, text := range []string{"", AnonymousPrincipal} {
+		name, err := ta.lib.ImportName(text, g.GSS_NT_ANONYMOUS)
+		if errors.Is(err, g.ErrBadNameType) {
+			t.Skip("anonymous names are not supported by this GSSAPI library")
+		}
+		assert.NoErrorFatal(err)
+		defer releaseName(name)
+
+		displayName, nameType, err := name.Display()
+		assert.NoError(err)
+		assert.Equal(AnonymousPrincipal, displayName)
+		assert.Equal(g.GSS_NT_ANONYMOUS, nameType)
+
+		anon, err := name.(*GssName).IsAnonymous()
+		assert.NoError(err)
+		assert.True(anon)
+	}
+
+	// realm-specific anonymous names round trip too
+	_, err = ta.lib.ImportName("WELLKNOWN/ANONYMOUS@", g.GSS_NT_ANONYMOUS)
+	assert.ErrorIs(err, g.ErrBadName)
+
+	realmAnon, err := ta.lib.ImportName("WELLKNOWN/ANONYMOUS@GOLANG-AUTH.IO", g.GSS_NT_ANONYMOUS)
+	assert.NoErrorFatal(err)
+	defer releaseName(realmAnon)
+
+	displayName, nameType, err := realmAnon.Display()
+	assert.NoError(err)
+	assert.Equal("WELLKNOWN/ANONYMOUS@GOLANG-AUTH.IO", displayName)
+	assert.Equal(g.GSS_NT_ANONYMOUS, nameType)
+
+	reimported, err := ta.lib.ImportName(displayName, nameType)
+	assert.NoErrorFatal(err)
+	defer releaseName(reimported)
+	same, err := realmAnon.Compare(reimported)
+	assert.NoError(err)
+	assert.True(same)
+
+	name, err := ta.lib.ImportName("fooname", g.GSS_NT_USER_NAME)
+	assert.NoErrorFatal(err)
+	defer releaseName(name)
+	anon, err := name.(*GssName).IsAnonymous()
+	assert.NoError(err)
+	assert.False(anon)
+}
+
+func TestNormalizeAnonymous(t *testing.T) {
+	assert := NewAssert(t)
+
+	tests := []struct {
+		name       string
+		nameType   g.GssNameType
+		expectName string
+		expectType g.GssNameType
+	}{
+		{"", g.GSS_NT_ANONYMOUS, AnonymousPrincipal, g.GSS_NT_ANONYMOUS},
+		{AnonymousPrincipal, g.GSS_NT_ANONYMOUS, AnonymousPrincipal, g.GSS_NT_ANONYMOUS},
+		{AnonymousPrincipal, g.GSS_KRB5_NT_PRINCIPAL_NAME, AnonymousPrincipal, g.GSS_NT_ANONYMOUS},
+		{"WELLKNOWN/ANONYMOUS@GOLANG-AUTH.IO", g.GSS_KRB5_NT_PRINCIPAL_NAME, "WELLKNOWN/ANONYMOUS@GOLANG-AUTH.IO", g.GSS_NT_ANONYMOUS},
+		{"robot@GOLANG-AUTH.IO", g.GSS_KRB5_NT_PRINCIPAL_NAME, "robot@GOLANG-AUTH.IO", g.GSS_KRB5_NT_PRINCIPAL_NAME},
+		{"", g.GSS_NT_USER_NAME, "", g.GSS_NT_USER_NAME},
+	}
+
+	for _, tt := range tests {
+		name, nameType := normalizeAnonymous(tt.name, tt.nameType)
+		assert.Equal(tt.expectName, name)
+		assert.Equal(tt.expectType, nameType)
+	}
+}
+
 func TestInquireMechsForName(t *testing.T) {
 
 	type testInfo struct {
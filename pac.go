@@ -0,0 +1,580 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// The Privilege Attribute Certificate issued by Active Directory, and by MIT and Heimdal KDCs
+// configured to do so, describes the groups of the client ([MS-PAC]).  Most of its buffers
+// are little endian structures; the logon information is encoded with NDR ([MS-RPCE] § 2.2.6).
+// Only the structure is decoded; the signatures are returned but not verified.
+
+// ErrMalformedPAC is returned when a PAC cannot be decoded
+var ErrMalformedPAC = errors.New("malformed PAC")
+
+// PACBufferType identifies the contents of a PAC buffer ([MS-PAC] § 2.4)
+type PACBufferType uint32
+
+const (
+	PACTypeLogonInfo      PACBufferType = 1
+	PACTypeCredentials    PACBufferType = 2
+	PACTypeServerChecksum PACBufferType = 6
+	PACTypeKDCChecksum    PACBufferType = 7
+	PACTypeClientInfo     PACBufferType = 10
+	PACTypeDelegationInfo PACBufferType = 11
+	PACTypeUPNDNSInfo     PACBufferType = 12
+	PACTypeClientClaims   PACBufferType = 13
+	PACTypeDeviceInfo     PACBufferType = 14
+	PACTypeDeviceClaims   PACBufferType = 15
+	PACTypeTicketChecksum PACBufferType = 16
+	PACTypeAttributes     PACBufferType = 17
+	PACTypeRequestor      PACBufferType = 18
+	PACTypeFullChecksum   PACBufferType = 19
+)
+
+// PACBuffer is one buffer of a PAC, decoded or not
+type PACBuffer struct {
+	Type PACBufferType
+	Data []byte
+}
+
+// PAC is a decoded Privilege Attribute Certificate.  The buffers that are understood are
+// decoded into the other fields; all of them are available in Buffers.
+type PAC struct {
+	Version uint32
+	Buffers []PACBuffer
+
+	LogonInfo      *KerbValidationInfo
+	ClientInfo     *PACClientInfo
+	UPNDNSInfo     *UPNDNSInfo
+	ServerChecksum *PACSignature
+	KDCChecksum    *PACSignature
+	TicketChecksum *PACSignature
+	FullChecksum   *PACSignature
+}
+
+// SID is a Windows security identifier
+type SID struct {
+	Revision            uint8
+	IdentifierAuthority uint64 // 48 bits
+	SubAuthority        []uint32
+}
+
+// String returns the SID in the usual S-1-5-21-... form
+func (s SID) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "S-%d-", s.Revision)
+	if s.IdentifierAuthority < 1<<32 {
+		b.WriteString(strconv.FormatUint(s.IdentifierAuthority, 10))
+	} else {
+		fmt.Fprintf(&b, "0x%012X", s.IdentifierAuthority)
+	}
+	for _, sub := range s.SubAuthority {
+		b.WriteByte('-')
+		b.WriteString(strconv.FormatUint(uint64(sub), 10))
+	}
+
+	return b.String()
+}
+
+// withRID returns the SID of the account rid in the domain s
+func (s SID) withRID(rid uint32) SID {
+	sub := make([]uint32, len(s.SubAuthority), len(s.SubAuthority)+1)
+	copy(sub, s.SubAuthority)
+	s.SubAuthority = append(sub, rid)
+
+	return s
+}
+
+// GroupMembership is a group of the domain that the client belongs to
+type GroupMembership struct {
+	RelativeID uint32
+	Attributes uint32
+}
+
+// SIDAndAttributes is a group from outside the domain that the client belongs to
+type SIDAndAttributes struct {
+	SID        SID
+	Attributes uint32
+}
+
+// KerbValidationInfo is the logon information of the client ([MS-PAC] § 2.5).  Times that
+// are not set, or never happen, are returned as the zero time.
+type KerbValidationInfo struct {
+	LogonTime              time.Time
+	LogoffTime             time.Time
+	KickOffTime            time.Time
+	PasswordLastSet        time.Time
+	PasswordCanChange      time.Time
+	PasswordMustChange     time.Time
+	EffectiveName          string
+	FullName               string
+	LogonScript            string
+	ProfilePath            string
+	HomeDirectory          string
+	HomeDirectoryDrive     string
+	LogonCount             uint16
+	BadPasswordCount       uint16
+	UserID                 uint32
+	PrimaryGroupID         uint32
+	GroupIDs               []GroupMembership
+	UserFlags              uint32
+	UserSessionKey         [16]byte
+	LogonServer            string
+	LogonDomainName        string
+	LogonDomainID          SID
+	UserAccountControl     uint32
+	SubAuthStatus          uint32
+	LastSuccessfulILogon   time.Time
+	LastFailedILogon       time.Time
+	FailedILogonCount      uint32
+	ExtraSIDs              []SIDAndAttributes
+	ResourceGroupDomainSID *SID
+	ResourceGroupIDs       []GroupMembership
+}
+
+// UserSID returns the SID of the client
+func (k *KerbValidationInfo) UserSID() SID {
+	return k.LogonDomainID.withRID(k.UserID)
+}
+
+// GroupSIDs returns the SIDs of all the groups the client belongs to: the groups of its
+// domain, the extra SIDs and the resource groups.
+func (k *KerbValidationInfo) GroupSIDs() []SID {
+	ret := make([]SID, 0, len(k.GroupIDs)+len(k.ExtraSIDs)+len(k.ResourceGroupIDs))
+	for _, g := range k.GroupIDs {
+		ret = append(ret, k.LogonDomainID.withRID(g.RelativeID))
+	}
+	for _, s := range k.ExtraSIDs {
+		ret = append(ret, s.SID)
+	}
+	if k.ResourceGroupDomainSID != nil {
+		for _, g := range k.ResourceGroupIDs {
+			ret = append(ret, k.ResourceGroupDomainSID.withRID(g.RelativeID))
+		}
+	}
+
+	return ret
+}
+
+// PACClientInfo holds the name of the client and the authentication time of the ticket
+// ([MS-PAC] § 2.7)
+type PACClientInfo struct {
+	ClientID time.Time
+	Name     string
+}
+
+// UPNDNSInfo flags
+const (
+	UPNDNSFlagNoUPN    = 0x1 // the UPN was constructed from the account name
+	UPNDNSFlagExtended = 0x2 // the SAM name and SID are present
+)
+
+// UPNDNSInfo holds the user principal name and DNS domain of the client ([MS-PAC] § 2.10)
+type UPNDNSInfo struct {
+	UPN           string
+	DNSDomainName string
+	Flags         uint32
+	SAMName       string // only with UPNDNSFlagExtended
+	SID           *SID   // only with UPNDNSFlagExtended
+}
+
+// PACSignature is one of the checksums over the PAC or the ticket ([MS-PAC] § 2.8)
+type PACSignature struct {
+	Type           int32 // Kerberos checksum type
+	Signature      []byte
+	RODCIdentifier uint16 // set when a read only domain controller issued the ticket
+}
+
+// DecodePAC decodes a PAC, such as the one returned by SecContext.PAC
+func DecodePAC(data []byte) (*PAC, error) {
+	r := &binReader{b: data, order: binary.LittleEndian, malformed: ErrMalformedPAC}
+	count := r.uint32()
+	pac := &PAC{Version: r.uint32()}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if pac.Version != 0 {
+		return nil, fmt.Errorf("%w: unsupported PAC version %d", ErrMalformedPAC, pac.Version)
+	}
+	if uint64(count)*16 > uint64(len(r.b)) {
+		return nil, fmt.Errorf("%w: %d buffers exceed the available data (%d bytes)", ErrMalformedPAC, count, len(data))
+	}
+
+	for range count {
+		bufType := PACBufferType(r.uint32())
+		size := uint64(r.uint32())
+		offset := uint64(r.uint32()) | uint64(r.uint32())<<32
+		if offset > uint64(len(data)) || size > uint64(len(data))-offset {
+			return nil, fmt.Errorf("%w: buffer type %d exceeds the available data (%d bytes)", ErrMalformedPAC, bufType, len(data))
+		}
+
+		buf := PACBuffer{Type: bufType, Data: bytes.Clone(data[offset : offset+size])}
+		pac.Buffers = append(pac.Buffers, buf)
+
+		if err := pac.decodeBuffer(buf); err != nil {
+			return nil, err
+		}
+	}
+
+	return pac, nil
+}
+
+func (pac *PAC) decodeBuffer(buf PACBuffer) error {
+	var err error
+	var dup bool
+	switch buf.Type {
+	case PACTypeLogonInfo:
+		dup = pac.LogonInfo != nil
+		pac.LogonInfo, err = decodeKerbValidationInfo(buf.Data)
+	case PACTypeClientInfo:
+		dup = pac.ClientInfo != nil
+		pac.ClientInfo, err = decodePACClientInfo(buf.Data)
+	case PACTypeUPNDNSInfo:
+		dup = pac.UPNDNSInfo != nil
+		pac.UPNDNSInfo, err = decodeUPNDNSInfo(buf.Data)
+	case PACTypeServerChecksum:
+		dup = pac.ServerChecksum != nil
+		pac.ServerChecksum, err = decodePACSignature(buf.Data)
+	case PACTypeKDCChecksum:
+		dup = pac.KDCChecksum != nil
+		pac.KDCChecksum, err = decodePACSignature(buf.Data)
+	case PACTypeTicketChecksum:
+		dup = pac.TicketChecksum != nil
+		pac.TicketChecksum, err = decodePACSignature(buf.Data)
+	case PACTypeFullChecksum:
+		dup = pac.FullChecksum != nil
+		pac.FullChecksum, err = decodePACSignature(buf.Data)
+	}
+
+	switch {
+	case dup:
+		return fmt.Errorf("%w: more than one buffer of type %d", ErrMalformedPAC, buf.Type)
+	case err != nil:
+		return fmt.Errorf("PAC buffer type %d: %w", buf.Type, err)
+	}
+
+	return nil
+}
+
+// filetime converts a Windows FILETIME, in 100ns intervals since 1601, to a time
+func filetime(ft uint64) time.Time {
+	// zero means not set and the maximum positive value means never
+	if ft == 0 || ft >= 0x7fffffffffffffff {
+		return time.Time{}
+	}
+
+	const epochOffset = 116444736000000000 // 1601 to 1970
+	ticks := int64(ft) - epochOffset
+
+	return time.Unix(ticks/1e7, (ticks%1e7)*100).UTC()
+}
+
+func utf16String(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+
+	return string(utf16.Decode(u))
+}
+
+// parseSID decodes a SID in its binary form
+func parseSID(b []byte) (SID, error) {
+	r := &binReader{b: b, order: binary.LittleEndian, malformed: ErrMalformedPAC}
+	sid := SID{Revision: r.uint8()}
+	count := int(r.uint8())
+	if auth := r.take(6); auth != nil {
+		sid.IdentifierAuthority = uint64(binary.BigEndian.Uint16(auth))<<32 | uint64(binary.BigEndian.Uint32(auth[2:]))
+	}
+	for range count {
+		sid.SubAuthority = append(sid.SubAuthority, r.uint32())
+	}
+	if r.err == nil && len(r.b) > 0 {
+		return sid, fmt.Errorf("%w: trailing data after SID", ErrMalformedPAC)
+	}
+
+	return sid, r.err
+}
+
+func decodePACClientInfo(b []byte) (*PACClientInfo, error) {
+	r := &binReader{b: b, order: binary.LittleEndian, malformed: ErrMalformedPAC}
+	info := &PACClientInfo{}
+	info.ClientID = filetime(uint64(r.uint32()) | uint64(r.uint32())<<32)
+	info.Name = utf16String(r.data16())
+
+	return info, r.err
+}
+
+func decodeUPNDNSInfo(b []byte) (*UPNDNSInfo, error) {
+	r := &binReader{b: b, order: binary.LittleEndian, malformed: ErrMalformedPAC}
+
+	// each string is given by its length and its offset from the start of the buffer
+	field := func() []byte {
+		length, offset := int(r.uint16()), int(r.uint16())
+		if r.err != nil {
+			return nil
+		}
+		if offset > len(b) || length > len(b)-offset || length%2 != 0 {
+			r.err = fmt.Errorf("%w: bad UPN_DNS_INFO string", ErrMalformedPAC)
+			return nil
+		}
+		return b[offset : offset+length]
+	}
+
+	info := &UPNDNSInfo{}
+	info.UPN = utf16String(field())
+	info.DNSDomainName = utf16String(field())
+	info.Flags = r.uint32()
+	if info.Flags&UPNDNSFlagExtended != 0 {
+		info.SAMName = utf16String(field())
+		sid := field()
+		if r.err == nil {
+			s, err := parseSID(sid)
+			if err != nil {
+				return nil, err
+			}
+			info.SID = &s
+		}
+	}
+
+	return info, r.err
+}
+
+// lengths of the checksum types that can be followed by an RODC identifier
+var pacSignatureLengths = map[int32]int{
+	-138: 16, // HMAC-MD5
+	15:   12, // HMAC-SHA1-96-AES128
+	16:   12, // HMAC-SHA1-96-AES256
+	19:   16, // HMAC-SHA256-128-AES128
+	20:   24, // HMAC-SHA384-192-AES256
+}
+
+func decodePACSignature(b []byte) (*PACSignature, error) {
+	r := &binReader{b: b, order: binary.LittleEndian, malformed: ErrMalformedPAC}
+	sig := &PACSignature{Type: int32(r.uint32())}
+
+	length, ok := pacSignatureLengths[sig.Type]
+	if !ok {
+		length = len(r.b)
+	}
+	sig.Signature = r.take(length)
+	if len(r.b) >= 2 {
+		sig.RODCIdentifier = r.uint16()
+	}
+
+	return sig, r.err
+}
+
+// ndrReader reads NDR data, which aligns each primitive to its size from the start of the
+// serialized data
+type ndrReader struct {
+	binReader
+	size int
+}
+
+func (r *ndrReader) align(n int) {
+	if pos := r.size - len(r.b); pos%n != 0 {
+		r.take(n - pos%n)
+	}
+}
+
+func (r *ndrReader) uint16() uint16 {
+	r.align(2)
+	return r.binReader.uint16()
+}
+
+func (r *ndrReader) uint32() uint32 {
+	r.align(4)
+	return r.binReader.uint32()
+}
+
+func (r *ndrReader) filetime() time.Time {
+	low := r.uint32()
+	return filetime(uint64(low) | uint64(r.uint32())<<32)
+}
+
+// ndrUnicodeString is the fixed part of an RPC_UNICODE_STRING; the characters follow later
+type ndrUnicodeString struct {
+	length    uint16
+	maxLength uint16
+	ptr       uint32
+}
+
+func (r *ndrReader) unicodeString() ndrUnicodeString {
+	return ndrUnicodeString{length: r.uint16(), maxLength: r.uint16(), ptr: r.uint32()}
+}
+
+// deferredString reads the characters of a string as a conformant varying array
+func (r *ndrReader) deferredString(s ndrUnicodeString) string {
+	if s.ptr == 0 {
+		return ""
+	}
+
+	maxCount, offset, count := r.uint32(), r.uint32(), r.uint32()
+	if r.err != nil {
+		return ""
+	}
+	if offset != 0 || count > maxCount || int(count)*2 != int(s.length) {
+		r.err = fmt.Errorf("%w: bad NDR string", ErrMalformedPAC)
+		return ""
+	}
+
+	return utf16String(r.take(int(count) * 2))
+}
+
+// conformance reads the size of a conformant array, which must be count
+func (r *ndrReader) conformance(count uint32, elemSize int) bool {
+	if maxCount := r.uint32(); r.err == nil && maxCount != count {
+		r.err = fmt.Errorf("%w: NDR array of %d elements, expected %d", ErrMalformedPAC, maxCount, count)
+	}
+	if r.err == nil && uint64(count)*uint64(elemSize) > uint64(len(r.b)) {
+		r.err = fmt.Errorf("%w: NDR array of %d elements exceeds the available data", ErrMalformedPAC, count)
+	}
+
+	return r.err == nil
+}
+
+func (r *ndrReader) groupMemberships(count uint32) []GroupMembership {
+	if !r.conformance(count, 8) {
+		return nil
+	}
+
+	ret := make([]GroupMembership, count)
+	for i := range ret {
+		ret[i] = GroupMembership{RelativeID: r.uint32(), Attributes: r.uint32()}
+	}
+
+	return ret
+}
+
+func (r *ndrReader) sid() SID {
+	count := r.uint32()
+	sid := SID{Revision: r.uint8()}
+	if subCount := r.uint8(); r.err == nil && uint32(subCount) != count {
+		r.err = fmt.Errorf("%w: bad NDR SID", ErrMalformedPAC)
+	}
+	if auth := r.take(6); auth != nil {
+		sid.IdentifierAuthority = uint64(binary.BigEndian.Uint16(auth))<<32 | uint64(binary.BigEndian.Uint32(auth[2:]))
+	}
+	if r.err != nil {
+		return sid
+	}
+	for range count {
+		sid.SubAuthority = append(sid.SubAuthority, r.uint32())
+	}
+
+	return sid
+}
+
+func (r *ndrReader) sidAndAttributes(count uint32) []SIDAndAttributes {
+	if !r.conformance(count, 8) {
+		return nil
+	}
+
+	ret := make([]SIDAndAttributes, count)
+	ptrs := make([]uint32, count)
+	for i := range ret {
+		ptrs[i] = r.uint32()
+		ret[i].Attributes = r.uint32()
+	}
+	for i, ptr := range ptrs {
+		if ptr != 0 {
+			ret[i].SID = r.sid()
+		}
+	}
+
+	return ret
+}
+
+// decodeKerbValidationInfo decodes the KERB_VALIDATION_INFO structure, serialized with the
+// NDR type serialization version 1 headers ([MS-RPCE] § 2.2.6)
+func decodeKerbValidationInfo(b []byte) (*KerbValidationInfo, error) {
+	r := &ndrReader{binReader{b: b, order: binary.LittleEndian, malformed: ErrMalformedPAC}, len(b)}
+
+	// common header: version 1, little endian, 8 byte header and filler
+	if hdr := r.take(8); hdr != nil && (hdr[0] != 1 || hdr[1] != 0x10 || binary.LittleEndian.Uint16(hdr[2:]) != 8) {
+		return nil, fmt.Errorf("%w: unsupported NDR serialization header", ErrMalformedPAC)
+	}
+	// private header: length of the serialized data and filler
+	_ = r.take(8)
+
+	if ref := r.uint32(); r.err == nil && ref == 0 {
+		return nil, fmt.Errorf("%w: no KERB_VALIDATION_INFO", ErrMalformedPAC)
+	}
+
+	k := &KerbValidationInfo{}
+	k.LogonTime = r.filetime()
+	k.LogoffTime = r.filetime()
+	k.KickOffTime = r.filetime()
+	k.PasswordLastSet = r.filetime()
+	k.PasswordCanChange = r.filetime()
+	k.PasswordMustChange = r.filetime()
+	effectiveName := r.unicodeString()
+	fullName := r.unicodeString()
+	logonScript := r.unicodeString()
+	profilePath := r.unicodeString()
+	homeDirectory := r.unicodeString()
+	homeDirectoryDrive := r.unicodeString()
+	k.LogonCount = r.uint16()
+	k.BadPasswordCount = r.uint16()
+	k.UserID = r.uint32()
+	k.PrimaryGroupID = r.uint32()
+	groupCount, groupPtr := r.uint32(), r.uint32()
+	k.UserFlags = r.uint32()
+	copy(k.UserSessionKey[:], r.take(16))
+	logonServer := r.unicodeString()
+	logonDomainName := r.unicodeString()
+	logonDomainIDPtr := r.uint32()
+	_ = r.take(8) // Reserved1
+	k.UserAccountControl = r.uint32()
+	k.SubAuthStatus = r.uint32()
+	k.LastSuccessfulILogon = r.filetime()
+	k.LastFailedILogon = r.filetime()
+	k.FailedILogonCount = r.uint32()
+	_ = r.uint32() // Reserved3
+	sidCount, extraSIDsPtr := r.uint32(), r.uint32()
+	resourceDomainPtr := r.uint32()
+	resourceCount, resourcePtr := r.uint32(), r.uint32()
+
+	// the referents of the pointers follow, in the same order
+	k.EffectiveName = r.deferredString(effectiveName)
+	k.FullName = r.deferredString(fullName)
+	k.LogonScript = r.deferredString(logonScript)
+	k.ProfilePath = r.deferredString(profilePath)
+	k.HomeDirectory = r.deferredString(homeDirectory)
+	k.HomeDirectoryDrive = r.deferredString(homeDirectoryDrive)
+	if groupPtr != 0 {
+		k.GroupIDs = r.groupMemberships(groupCount)
+	}
+	k.LogonServer = r.deferredString(logonServer)
+	k.LogonDomainName = r.deferredString(logonDomainName)
+	if logonDomainIDPtr != 0 {
+		k.LogonDomainID = r.sid()
+	}
+	if extraSIDsPtr != 0 {
+		k.ExtraSIDs = r.sidAndAttributes(sidCount)
+	}
+	if resourceDomainPtr != 0 {
+		sid := r.sid()
+		k.ResourceGroupDomainSID = &sid
+	}
+	if resourcePtr != 0 {
+		k.ResourceGroupIDs = r.groupMemberships(resourceCount)
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return k, nil
+}
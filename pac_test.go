@@ -0,0 +1,501 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// ndrWriter builds NDR data for the decoder tests
+type ndrWriter struct {
+	b []byte
+}
+
+func (w *ndrWriter) align(n int) {
+	for len(w.b)%n != 0 {
+		w.b = append(w.b, 0)
+	}
+}
+
+func (w *ndrWriter) uint8(v uint8) {
+	w.b = append(w.b, v)
+}
+
+func (w *ndrWriter) uint16(v uint16) {
+	w.align(2)
+	w.b = binary.LittleEndian.AppendUint16(w.b, v)
+}
+
+func (w *ndrWriter) uint32(v uint32) {
+	w.align(4)
+	w.b = binary.LittleEndian.AppendUint32(w.b, v)
+}
+
+func (w *ndrWriter) filetime(t time.Time) {
+	ft := uint64(t.Unix()*1e7 + 116444736000000000)
+	w.uint32(uint32(ft))
+	w.uint32(uint32(ft >> 32))
+}
+
+func utf16Bytes(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+// unicodeString writes the fixed part of an RPC_UNICODE_STRING; the characters are written
+// by deferredString
+func (w *ndrWriter) unicodeString(s string, ptr uint32) {
+	w.uint16(uint16(len(utf16Bytes(s))))
+	w.uint16(uint16(len(utf16Bytes(s))))
+	w.uint32(ptr)
+}
+
+func (w *ndrWriter) deferredString(s string) {
+	n := uint32(len(utf16Bytes(s)) / 2)
+	w.uint32(n)
+	w.uint32(0)
+	w.uint32(n)
+	w.b = append(w.b, utf16Bytes(s)...)
+}
+
+func (w *ndrWriter) sid(s SID) {
+	w.uint32(uint32(len(s.SubAuthority)))
+	w.uint8(s.Revision)
+	w.uint8(uint8(len(s.SubAuthority)))
+	w.b = append(w.b, byte(s.IdentifierAuthority>>40), byte(s.IdentifierAuthority>>32))
+	w.b = binary.BigEndian.AppendUint32(w.b, uint32(s.IdentifierAuthority))
+	for _, sub := range s.SubAuthority {
+		w.uint32(sub)
+	}
+}
+
+func sidBytes(s SID) []byte {
+	w := &ndrWriter{}
+	w.sid(s)
+	return w.b[4:]
+}
+
+var (
+	testDomainSID   = SID{Revision: 1, IdentifierAuthority: 5, SubAuthority: []uint32{21, 1111, 2222, 3333}}
+	testResourceSID = SID{Revision: 1, IdentifierAuthority: 5, SubAuthority: []uint32{21, 4444, 5555, 6666}}
+	testExtraSID    = SID{Revision: 1, IdentifierAuthority: 18, SubAuthority: []uint32{1}}
+	testLogonTime   = time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+)
+
+// testKerbValidationInfo encodes a KERB_VALIDATION_INFO for alice, with NDR headers
+func testKerbValidationInfo() []byte {
+	w := &ndrWriter{}
+	w.b = append(w.b, 1, 0x10, 8, 0, 0xcc, 0xcc, 0xcc, 0xcc) // common header
+	w.b = append(w.b, 0, 0, 0, 0, 0, 0, 0, 0)                // private header
+	w.uint32(0x20000)                                        // top level pointer
+
+	w.filetime(testLogonTime) // LogonTime
+	w.uint32(0xffffffff)      // LogoffTime: never
+	w.uint32(0x7fffffff)
+	w.uint32(0xffffffff) // KickOffTime: never
+	w.uint32(0x7fffffff)
+	w.filetime(testLogonTime.Add(-24 * time.Hour)) // PasswordLastSet
+	w.uint32(0)                                    // PasswordCanChange: not set
+	w.uint32(0)
+	w.uint32(0xffffffff) // PasswordMustChange: never
+	w.uint32(0x7fffffff)
+	w.unicodeString("alice", 0x20004)         // EffectiveName
+	w.unicodeString("Alice Liddell", 0x20008) // FullName
+	w.unicodeString("", 0x2000c)              // LogonScript
+	w.unicodeString("", 0)                    // ProfilePath
+	w.unicodeString("", 0x20010)              // HomeDirectory
+	w.unicodeString("", 0x20014)              // HomeDirectoryDrive
+	w.uint16(42)                              // LogonCount
+	w.uint16(1)                               // BadPasswordCount
+	w.uint32(1105)                            // UserId
+	w.uint32(513)                             // PrimaryGroupId
+	w.uint32(2)                               // GroupCount
+	w.uint32(0x20018)                         // GroupIds
+	w.uint32(0x220)                           // UserFlags: extra SIDs and resource groups
+	w.b = append(w.b, make([]byte, 16)...)    // UserSessionKey
+	w.unicodeString("DC1", 0x2001c)           // LogonServer
+	w.unicodeString("WONDERLAND", 0x20020)    // LogonDomainName
+	w.uint32(0x20024)                         // LogonDomainId
+	w.uint32(0)                               // Reserved1
+	w.uint32(0)
+	w.uint32(0x210) // UserAccountControl
+	w.uint32(0)     // SubAuthStatus
+	w.uint32(0)     // LastSuccessfulILogon
+	w.uint32(0)
+	w.uint32(0) // LastFailedILogon
+	w.uint32(0)
+	w.uint32(0)       // FailedILogonCount
+	w.uint32(0)       // Reserved3
+	w.uint32(1)       // SidCount
+	w.uint32(0x20028) // ExtraSids
+	w.uint32(0x2002c) // ResourceGroupDomainSid
+	w.uint32(1)       // ResourceGroupCount
+	w.uint32(0x20030) // ResourceGroupIds
+
+	w.deferredString("alice")
+	w.deferredString("Alice Liddell")
+	w.deferredString("")
+	w.deferredString("")
+	w.deferredString("")
+	w.uint32(2) // GroupIds
+	w.uint32(513)
+	w.uint32(7)
+	w.uint32(1120)
+	w.uint32(7)
+	w.deferredString("DC1")
+	w.deferredString("WONDERLAND")
+	w.sid(testDomainSID)
+	w.uint32(1) // ExtraSids
+	w.uint32(0x20034)
+	w.uint32(7)
+	w.sid(testExtraSID)
+	w.sid(testResourceSID)
+	w.uint32(1) // ResourceGroupIds
+	w.uint32(1301)
+	w.uint32(0x20000007)
+
+	binary.LittleEndian.PutUint32(w.b[8:], uint32(len(w.b)-16))
+	return w.b
+}
+
+func testClientInfo() []byte {
+	w := &ndrWriter{}
+	w.filetime(testLogonTime)
+	w.uint16(uint16(len(utf16Bytes("alice"))))
+	w.b = append(w.b, utf16Bytes("alice")...)
+	return w.b
+}
+
+func testUPNDNSInfo() []byte {
+	upn := utf16Bytes("alice@wonderland.example")
+	dns := utf16Bytes("WONDERLAND.EXAMPLE")
+	sam := utf16Bytes("alice")
+	sid := sidBytes(testDomainSID.withRID(1105))
+
+	w := &ndrWriter{}
+	offset := uint16(24)
+	for _, s := range [][]byte{upn, dns} {
+		w.uint16(uint16(len(s)))
+		w.uint16(offset)
+		offset += uint16(len(s))
+	}
+	w.uint32(UPNDNSFlagExtended)
+	for _, s := range [][]byte{sam, sid} {
+		w.uint16(uint16(len(s)))
+		w.uint16(offset)
+		offset += uint16(len(s))
+	}
+	w.align(8)
+	for _, s := range [][]byte{upn, dns, sam, sid} {
+		w.b = append(w.b, s...)
+	}
+	return w.b
+}
+
+func testSignature(ctype int32, length int, rodc bool) []byte {
+	w := &ndrWriter{}
+	w.uint32(uint32(ctype))
+	for i := range length {
+		w.b = append(w.b, byte(i))
+	}
+	if rodc {
+		w.b = binary.LittleEndian.AppendUint16(w.b, 3)
+	}
+	return w.b
+}
+
+// buildPAC assembles a PAC from its buffers, aligning each to 8 bytes
+func buildPAC(bufs []PACBuffer) []byte {
+	hdrLen := 8 + 16*len(bufs)
+	hdr := binary.LittleEndian.AppendUint32(nil, uint32(len(bufs)))
+	hdr = binary.LittleEndian.AppendUint32(hdr, 0)
+
+	var body []byte
+	for _, buf := range bufs {
+		for (hdrLen+len(body))%8 != 0 {
+			body = append(body, 0)
+		}
+		hdr = binary.LittleEndian.AppendUint32(hdr, uint32(buf.Type))
+		hdr = binary.LittleEndian.AppendUint32(hdr, uint32(len(buf.Data)))
+		hdr = binary.LittleEndian.AppendUint64(hdr, uint64(hdrLen+len(body)))
+		body = append(body, buf.Data...)
+	}
+
+	return append(hdr, body...)
+}
+
+func TestSIDString(t *testing.T) {
+	assert := NewAssert(t)
+
+	assert.Equal("S-1-5-21-1111-2222-3333", testDomainSID.String())
+	assert.Equal("S-1-5-21-1111-2222-3333-500", testDomainSID.withRID(500).String())
+	assert.Equal("S-1-5-21-1111-2222-3333", testDomainSID.String()) // withRID leaves the domain alone
+	assert.Equal("S-1-0x000100000001-7", SID{Revision: 1, IdentifierAuthority: 1<<32 | 1, SubAuthority: []uint32{7}}.String())
+
+	sid, err := parseSID(sidBytes(testDomainSID))
+	assert.NoError(err)
+	assert.Equal(testDomainSID, sid)
+}
+
+func TestDecodePAC(t *testing.T) {
+	assert := NewAssert(t)
+
+	data := buildPAC([]PACBuffer{
+		{PACTypeLogonInfo, testKerbValidationInfo()},
+		{PACTypeClientInfo, testClientInfo()},
+		{PACTypeUPNDNSInfo, testUPNDNSInfo()},
+		{PACTypeAttributes, []byte{2, 0, 0, 0, 1, 0, 0, 0}},
+		{PACTypeServerChecksum, testSignature(16, 12, false)},
+		{PACTypeKDCChecksum, testSignature(16, 12, true)},
+	})
+
+	pac, err := DecodePAC(data)
+	assert.NoErrorFatal(err)
+	assert.Equal(uint32(0), pac.Version)
+	assert.Len(pac.Buffers, 6)
+	assert.Equal(PACTypeAttributes, pac.Buffers[3].Type)
+	assert.Nil(pac.TicketChecksum)
+	assert.Nil(pac.FullChecksum)
+
+	k := pac.LogonInfo
+	if !assert.NotNil(k) {
+		return
+	}
+	assert.Equal(testLogonTime, k.LogonTime)
+	assert.True(k.LogoffTime.IsZero())
+	assert.True(k.KickOffTime.IsZero())
+	assert.Equal(testLogonTime.Add(-24*time.Hour), k.PasswordLastSet)
+	assert.True(k.PasswordCanChange.IsZero())
+	assert.True(k.PasswordMustChange.IsZero())
+	assert.Equal("alice", k.EffectiveName)
+	assert.Equal("Alice Liddell", k.FullName)
+	assert.Equal("", k.ProfilePath)
+	assert.Equal(uint16(42), k.LogonCount)
+	assert.Equal(uint16(1), k.BadPasswordCount)
+	assert.Equal(uint32(513), k.PrimaryGroupID)
+	assert.Equal([]GroupMembership{{513, 7}, {1120, 7}}, k.GroupIDs)
+	assert.Equal("DC1", k.LogonServer)
+	assert.Equal("WONDERLAND", k.LogonDomainName)
+	assert.Equal(uint32(0x210), k.UserAccountControl)
+	assert.Equal("S-1-5-21-1111-2222-3333-1105", k.UserSID().String())
+
+	var groups []string
+	for _, sid := range k.GroupSIDs() {
+		groups = append(groups, sid.String())
+	}
+	assert.Equal([]string{
+		"S-1-5-21-1111-2222-3333-513",
+		"S-1-5-21-1111-2222-3333-1120",
+		"S-1-18-1",
+		"S-1-5-21-4444-5555-6666-1301",
+	}, groups)
+
+	if !assert.NotNil(pac.ClientInfo) {
+		return
+	}
+	assert.Equal(testLogonTime, pac.ClientInfo.ClientID)
+	assert.Equal("alice", pac.ClientInfo.Name)
+
+	u := pac.UPNDNSInfo
+	if !assert.NotNil(u) {
+		return
+	}
+	assert.Equal("alice@wonderland.example", u.UPN)
+	assert.Equal("WONDERLAND.EXAMPLE", u.DNSDomainName)
+	assert.Equal("alice", u.SAMName)
+	if !assert.NotNil(u.SID) {
+		return
+	}
+	assert.Equal("S-1-5-21-1111-2222-3333-1105", u.SID.String())
+
+	if !assert.NotNil(pac.ServerChecksum) {
+		return
+	}
+	assert.Equal(int32(16), pac.ServerChecksum.Type)
+	assert.Len(pac.ServerChecksum.Signature, 12)
+	assert.Equal(uint16(0), pac.ServerChecksum.RODCIdentifier)
+	if !assert.NotNil(pac.KDCChecksum) {
+		return
+	}
+	assert.Equal(uint16(3), pac.KDCChecksum.RODCIdentifier)
+}
+
+func TestDecodePACMalformed(t *testing.T) {
+	logonInfo := testKerbValidationInfo()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"short header", []byte{1, 0, 0, 0}},
+		{"bad version", []byte{0, 0, 0, 0, 1, 0, 0, 0}},
+		{"too many buffers", []byte{0xff, 0xff, 0, 0, 0, 0, 0, 0}},
+		{"buffer past end", append(buildPAC([]PACBuffer{{PACTypeClientInfo, testClientInfo()}})[:20], make([]byte, 4)...)},
+		{"truncated logon info", buildPAC([]PACBuffer{{PACTypeLogonInfo, logonInfo[:len(logonInfo)-8]}})},
+		{"bad NDR header", buildPAC([]PACBuffer{{PACTypeLogonInfo, append([]byte{2}, logonInfo[1:]...)}})},
+		{"duplicate logon info", buildPAC([]PACBuffer{{PACTypeLogonInfo, logonInfo}, {PACTypeLogonInfo, logonInfo}})},
+		{"truncated client info", buildPAC([]PACBuffer{{PACTypeClientInfo, testClientInfo()[:12]}})},
+		{"truncated UPN", buildPAC([]PACBuffer{{PACTypeUPNDNSInfo, testUPNDNSInfo()[:30]}})},
+		{"truncated signature", buildPAC([]PACBuffer{{PACTypeServerChecksum, testSignature(16, 8, false)}})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := NewAssert(t)
+			_, err := DecodePAC(tt.data)
+			assert.ErrorIs(err, ErrMalformedPAC)
+		})
+	}
+}
+
+// msPACSample is the example PAC from [MS-PAC], issued for lzhu@NTDEV by NTDEV-DC-05
+const msPACSample = "040000000000000001000000b004000048000000000000000a00000012000000f804000000000000060000001400000010050000000000000700000014000000280500000000000001100800cccccccca00400000000000000000200d186660f656ac601ffffffffffffff7fffffffffffffff7f17d439fe784ac6011794a328424bc601175424977a81c60108000800040002002400240008000200120012000c0002000000000010000200000000001400020000000000180002005410000097792c00010200001a0000001c000200200000000000000000000000000000000000000016001800200002000a000c002400020028000200000000000000000010000000000000000000000000000000000000000000000000000000000000000d0000002c0002000000000000000000000000000400000000000000040000006c007a00680075001200000000000000120000004c0069007100690061006e00670028004c006100720072007900290020005a00680075000900000000000000090000006e0074006400730032002e0062006100740000000000000000000000000000000000000000000000000000000000000000000000000000001a00000061c433000700000009c32d00070000005eb4320007000000010200000700000097b92c00070000002bf1320007000000ce30330007000000a72e2e00070000002af132000700000098b92c000700000062c4330007000000940133000700000076c4330007000000aefe2d000700000032d22c00070000001608320007000000425b2e00070000005fb4320007000000ca9c35000700000085442d0007000000c2f0320007000000e9ea310007000000ed8e2e0007000000b6eb310007000000ab2e2e0007000000720e2e00070000000c000000000000000b0000004e0054004400450056002d00440043002d003000350000000600000000000000050000004e0054004400450056000000040000000104000000000005150000005951b81766725d2564633b0b0d0000003000020007000000340002000700002038000200070000203c000200070000204000020007000020440002000700002048000200070000204c000200070000205000020007000020540002000700002058000200070000205c00020007000020600002000700002005000000010500000000000515000000b9301b2eb7414c6c8c3b351501020000050000000105000000000005150000005951b81766725d2564633b0b74542f00050000000105000000000005150000005951b81766725d2564633b0be8383200050000000105000000000005150000005951b81766725d2564633b0bcd383200050000000105000000000005150000005951b81766725d2564633b0b5db43200050000000105000000000005150000005951b81766725d2564633b0b41163500050000000105000000000005150000005951b81766725d2564633b0be8ea3100050000000105000000000005150000005951b81766725d2564633b0bc1193200050000000105000000000005150000005951b81766725d2564633b0b29f13200050000000105000000000005150000005951b81766725d2564633b0b0f5f2e00050000000105000000000005150000005951b81766725d2564633b0b2f5b2e00050000000105000000000005150000005951b81766725d2564633b0bef8f3100050000000105000000000005150000005951b81766725d2564633b0b075f2e00000000000049d90e656ac60108006c007a006800750000000000000076ffffff41edce9a34815d3aef7bc98874805d250000000076fffffff7a534dab2c02986efe0fbe5110a4f3200000000"
+
+// adPAC was issued by an Active Directory domain controller for testuser1@TEST.GOKRB5, as
+// captured by the gokrb5 project for its tests
+const adPAC = "0500000000000000010000002802000058000000000000000a0000001c00000080020000000000000c00000058000000a0020000000000000600000010000000f8020000000000000700000014000000080300000000000001100800cccccccc180200000000000000000200058e4fdd80c6d201ffffffffffffff7fffffffffffffff7fcc27969c39c6d201cce7ffc602c7d201ffffffffffffff7f12001200040002001600160008000200000000000c000200000000001000020000000000140002000000000018000200d80000005104000001020000050000001c000200200000000000000000000000000000000000000008000a002000020008000a00240002002800020000000000000000001002000000000000000000000000000000000000000000000000000000000000020000002c00020000000000000000000000000009000000000000000900000074006500730074007500730065007200310000000b000000000000000b000000540065007300740031002000550073006500720031000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000050000000102000007000000540400000700000055040000070000005b040000070000005c0400000700000005000000000000000400000041004400440043000500000000000000040000005400450053005400040000000104000000000005150000004c86cebca07160e63fdce8870200000030000200070000203400020007000020050000000105000000000005150000004c86cebca07160e63fdce8875a040000050000000105000000000005150000004c86cebca07160e63fdce8875704000000000000808dd1dc80c6d2011200740065007300740075007300650072003100000000002a001000160040000000000000000000740065007300740075007300650072003100400074006500730074002e0067006f006b0072006200350000000000000054004500530054002e0047004f004b005200420035000000100000001e251d98d552be7df384f55076ffffff340be28b48765d0519ee9346cf53d82200000000"
+
+func TestDecodePACSample(t *testing.T) {
+	assert := NewAssert(t)
+
+	data, err := hex.DecodeString(msPACSample)
+	assert.NoErrorFatal(err)
+	pac, err := DecodePAC(data)
+	assert.NoErrorFatal(err)
+	assert.Len(pac.Buffers, 4)
+
+	k := pac.LogonInfo
+	if !assert.NotNil(k) {
+		return
+	}
+	assert.Equal(time.Date(2006, 4, 28, 1, 42, 50, 925640100, time.UTC), k.LogonTime)
+	assert.True(k.LogoffTime.IsZero())
+	assert.Equal("lzhu", k.EffectiveName)
+	assert.Equal("Liqiang(Larry) Zhu", k.FullName)
+	assert.Equal("ntds2.bat", k.LogonScript)
+	assert.Equal(uint16(4180), k.LogonCount)
+	assert.Equal("NTDEV-DC-05", k.LogonServer)
+	assert.Equal("NTDEV", k.LogonDomainName)
+	assert.Equal("S-1-5-21-397955417-626881126-188441444", k.LogonDomainID.String())
+	assert.Equal("S-1-5-21-397955417-626881126-188441444-2914711", k.UserSID().String())
+	assert.Len(k.GroupIDs, 26)
+	assert.Len(k.ExtraSIDs, 13)
+	assert.Nil(k.ResourceGroupDomainSID)
+
+	groups := k.GroupSIDs()
+	if assert.Len(groups, 39) {
+		assert.Equal("S-1-5-21-397955417-626881126-188441444-3392609", groups[0].String())
+		assert.Equal("S-1-5-21-773533881-1816936887-355810188-513", groups[26].String())
+		assert.Equal("S-1-5-21-397955417-626881126-188441444-3038983", groups[38].String())
+	}
+
+	if assert.NotNil(pac.ClientInfo) {
+		assert.Equal("lzhu", pac.ClientInfo.Name)
+	}
+	assert.Nil(pac.UPNDNSInfo)
+	if assert.NotNil(pac.ServerChecksum) && assert.NotNil(pac.KDCChecksum) {
+		assert.Equal(int32(-138), pac.ServerChecksum.Type) // HMAC-MD5
+		assert.Equal(int32(-138), pac.KDCChecksum.Type)
+		assert.Len(pac.KDCChecksum.Signature, 16)
+	}
+}
+
+func TestDecodePACActiveDirectory(t *testing.T) {
+	assert := NewAssert(t)
+
+	data, err := hex.DecodeString(adPAC)
+	assert.NoErrorFatal(err)
+	pac, err := DecodePAC(data)
+	assert.NoErrorFatal(err)
+	assert.Len(pac.Buffers, 5)
+
+	k := pac.LogonInfo
+	if !assert.NotNil(k) {
+		return
+	}
+	assert.Equal("testuser1", k.EffectiveName)
+	assert.Equal("Test1 User1", k.FullName)
+	assert.Equal("ADDC", k.LogonServer)
+	assert.Equal("TEST", k.LogonDomainName)
+	assert.Equal(uint32(513), k.PrimaryGroupID)
+	assert.Equal("S-1-5-21-3167651404-3865080224-2280184895-1105", k.UserSID().String())
+
+	var groups []string
+	for _, sid := range k.GroupSIDs() {
+		groups = append(groups, sid.String())
+	}
+	assert.Equal([]string{
+		"S-1-5-21-3167651404-3865080224-2280184895-513",
+		"S-1-5-21-3167651404-3865080224-2280184895-1108",
+		"S-1-5-21-3167651404-3865080224-2280184895-1109",
+		"S-1-5-21-3167651404-3865080224-2280184895-1115",
+		"S-1-5-21-3167651404-3865080224-2280184895-1116",
+		"S-1-5-21-3167651404-3865080224-2280184895-1114",
+		"S-1-5-21-3167651404-3865080224-2280184895-1111",
+	}, groups)
+
+	if assert.NotNil(pac.ClientInfo) {
+		assert.Equal("testuser1", pac.ClientInfo.Name)
+		assert.Equal(k.LogonTime.Truncate(time.Second), pac.ClientInfo.ClientID)
+	}
+	if assert.NotNil(pac.UPNDNSInfo) {
+		assert.Equal("testuser1@test.gokrb5", pac.UPNDNSInfo.UPN)
+		assert.Equal("TEST.GOKRB5", pac.UPNDNSInfo.DNSDomainName)
+		assert.Equal(uint32(0), pac.UPNDNSInfo.Flags)
+		assert.Nil(pac.UPNDNSInfo.SID)
+	}
+	if assert.NotNil(pac.ServerChecksum) && assert.NotNil(pac.KDCChecksum) {
+		assert.Equal(int32(16), pac.ServerChecksum.Type) // HMAC-SHA1-96-AES256
+		assert.Len(pac.ServerChecksum.Signature, 12)
+		assert.Equal(int32(-138), pac.KDCChecksum.Type)
+	}
+}
+
+func FuzzDecodePAC(f *testing.F) {
+	for _, s := range []string{msPACSample, adPAC} {
+		data, err := hex.DecodeString(s)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add(buildPAC([]PACBuffer{
+		{PACTypeLogonInfo, testKerbValidationInfo()},
+		{PACTypeClientInfo, testClientInfo()},
+		{PACTypeUPNDNSInfo, testUPNDNSInfo()},
+		{PACTypeKDCChecksum, testSignature(16, 12, true)},
+	}))
+	f.Add([]byte{0xff, 0xff, 0, 0, 0, 0, 0, 0})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		pac, err := DecodePAC(data)
+		if err != nil {
+			if !errors.Is(err, ErrMalformedPAC) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			return
+		}
+
+		for _, buf := range pac.Buffers {
+			if !bytes.Contains(data, buf.Data) {
+				t.Errorf("buffer type %d is not part of the input", buf.Type)
+			}
+		}
+		if pac.LogonInfo != nil {
+			_ = pac.LogonInfo.UserSID().String()
+			for _, sid := range pac.LogonInfo.GroupSIDs() {
+				_ = sid.String()
+			}
+		}
+	})
+}
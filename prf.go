@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+/*
+#include "gss.h"
+#include <sys/types.h>
+
+// The function pointer is set to the library function by the init function using symbolMap.Apply()
+OM_uint32 (*__gogssapi_pseudo_random)(OM_uint32 *minor_status,
+	gss_ctx_id_t context,
+	int prf_key,
+	const gss_buffer_t prf_in,
+	ssize_t desired_output_len,
+	gss_buffer_t prf_out) = NULL;
+
+OM_uint32 _gogssapi_pseudo_random(OM_uint32 *minor_status, gss_ctx_id_t context, int prf_key, const gss_buffer_t prf_in, ssize_t desired_output_len, gss_buffer_t prf_out) {
+	if( __gogssapi_pseudo_random == NULL ) {
+		*minor_status = 0;
+		return GSS_S_UNAVAILABLE;
+	}
+	return _GOGSSAPI_LOCKED(__gogssapi_pseudo_random(minor_status, context, prf_key, prf_in, desired_output_len, prf_out));
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"math"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// Map optional symbols from the GSSAPI library to the wrapper function pointers
+var prfSymbols = symbolMap{
+	"gss_pseudo_random": &C.__gogssapi_pseudo_random,
+}
+
+func init() {
+	prfSymbols.Apply()
+}
+
+// PRFKey selects the key used by PseudoRandom (RFC 4401 § 2)
+type PRFKey int
+
+const (
+	// The key the context protects messages with: the acceptor subkey if there is one.
+	// Gives the same output on both sides of the context.
+	PRFKeyFull PRFKey = 0 // GSS_C_PRF_KEY_FULL
+	// The key known to both peers before any subkey negotiation: for Kerberos, the
+	// initiator subkey or the ticket session key
+	PRFKeyPartial PRFKey = 1 // GSS_C_PRF_KEY_PARTIAL
+)
+
+// PseudoRandom implements GSS_Pseudo_random from RFC 4401, deriving outLen bytes from the
+// context key selected by prfKey and the input prfIn.  The Kerberos mechanism uses the PRF+
+// construction from RFC 4402, so the output matches other implementations, including SSPI.
+// The context must be fully established.  GSS_S_UNAVAILABLE is returned if the library does
+// not support the function.
+func (c *SecContext) PseudoRandom(prfKey PRFKey, prfIn []byte, outLen int) ([]byte, error) {
+	if prfKey != PRFKeyFull && prfKey != PRFKeyPartial {
+		return nil, fmt.Errorf("bad PRF key %d: %w", prfKey, g.ErrFailure)
+	}
+	if outLen <= 0 {
+		return nil, fmt.Errorf("bad output length %d: %w", outLen, g.ErrFailure)
+	}
+	// the C bindings support a 32 bit max message size..
+	if len(prfIn) > math.MaxUint32 || outLen > math.MaxInt32 {
+		return nil, ErrTooLarge
+	}
+
+	cPrfIn, pinner := bytesToCBuffer(prfIn, nil)
+	defer pinner.Unpin()
+
+	var cMinor C.OM_uint32
+	var cPrfOut C.gss_buffer_desc = C.gss_empty_buffer // allocated by GSSAPI; released by *1
+	cMajor := C._gogssapi_pseudo_random(&cMinor, c.id, C.int(prfKey), &cPrfIn, C.ssize_t(outLen), &cPrfOut)
+	if cMajor != C.GSS_S_COMPLETE {
+		return nil, makeMechStatus(cMajor, cMinor, c.mech)
+	}
+
+	defer C.gss_release_buffer(&cMinor, &cPrfOut) // *1  Release GSSAPI allocated buffer
+
+	return C.GoBytes(cPrfOut.value, C.int(cPrfOut.length)), nil
+}
@@ -0,0 +1,229 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"crypto/aes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// The Kerberos PRF+ construction of RFC 4402 for the AES enctypes of RFC 3962, used to check
+// the output of the library against the session key
+
+// nFold implements the n-fold operation of RFC 3961 § 5.1, returning n bytes
+func nFold(in []byte, n int) []byte {
+	lcm := n * len(in) / gcd(n, len(in))
+
+	// copies of the input, each rotated 13 bits further right than the last
+	buf := make([]byte, 0, lcm)
+	for i := 0; len(buf) < lcm; i++ {
+		buf = append(buf, rotateRight(in, 13*i)...)
+	}
+
+	// add the n byte blocks with end-around carry
+	out := make([]byte, n)
+	for off := 0; off < lcm; off += n {
+		carry := 0
+		for i := n - 1; i >= 0; i-- {
+			sum := int(out[i]) + int(buf[off+i]) + carry
+			out[i], carry = byte(sum), sum>>8
+		}
+		for i := n - 1; carry != 0 && i >= 0; i-- {
+			sum := int(out[i]) + carry
+			out[i], carry = byte(sum), sum>>8
+		}
+	}
+
+	return out
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func rotateRight(in []byte, bits int) []byte {
+	nbits := len(in) * 8
+	out := make([]byte, len(in))
+	for i := range nbits {
+		src := (i - bits%nbits + nbits) % nbits
+		if in[src/8]&(0x80>>(src%8)) != 0 {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// aesDK derives a key from an AES protocol key and a constant (RFC 3961 § 5.1, RFC 3962 § 6)
+func aesDK(key, constant []byte) []byte {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+
+	in := nFold(constant, aes.BlockSize)
+	var out []byte
+	for len(out) < len(key) {
+		next := make([]byte, aes.BlockSize)
+		block.Encrypt(next, in)
+		out = append(out, next...)
+		in = next
+	}
+
+	return out[:len(key)]
+}
+
+// aesPRF is the pseudo-random function of the AES enctypes (RFC 3962 § 6)
+func aesPRF(key, in []byte) []byte {
+	sum := sha1.Sum(in)
+	block, err := aes.NewCipher(aesDK(key, []byte("prf")))
+	if err != nil {
+		panic(err)
+	}
+
+	out := make([]byte, aes.BlockSize)
+	block.Encrypt(out, sum[:aes.BlockSize])
+	return out
+}
+
+// aesPRFPlus is PRF+ from RFC 4402.  The 32 bit big endian counter starts at zero, as it does
+// in MIT, Heimdal and SSPI.
+func aesPRFPlus(key, in []byte, outLen int) []byte {
+	var out []byte
+	for n := uint32(0); len(out) < outLen; n++ {
+		out = append(out, aesPRF(key, append(binary.BigEndian.AppendUint32(nil, n), in...))...)
+	}
+	return out[:outLen]
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestAESPRFPlusHelpers(t *testing.T) {
+	assert := NewAssert(t)
+
+	// RFC 3961 appendix A.1
+	folds := []struct {
+		in  string
+		n   int
+		out string
+	}{
+		{"012345", 8, "be072631276b1955"},
+		{"password", 7, "78a07b6caf85fa"},
+		{"Rough Consensus, and Running Code", 8, "bb6ed30870b7f0e0"},
+		{"password", 21, "59e4a8ca7c0385c3c37b3f6d2000247cb6e6bd5b3e"},
+		{"MASSACHVSETTS INSTITVTE OF TECHNOLOGY", 24, "db3b0d8f0b061e603282b308a50841229ad798fab9540c1b"},
+		{"Q", 21, "518a54a215a8452a518a54a215a8452a518a54a215"},
+		{"ba", 21, "fb25d531ae8974499f52fd92ea9857c4ba24cf297e"},
+		{"kerberos", 8, "6b65726265726f73"},
+		{"kerberos", 16, "6b65726265726f737b9b5b2b93132b93"},
+		{"kerberos", 21, "8372c236344e5f1550cd0747e15d62ca7a5a3bcea4"},
+		{"kerberos", 32, "6b65726265726f737b9b5b2b93132b935c9bdcdad95c9899c4cae4dee6d6cae4"},
+	}
+	for _, f := range folds {
+		assert.Equal(f.out, hex.EncodeToString(nFold([]byte(f.in), f.n)), "%d-fold(%q)", f.n*8, f.in)
+	}
+
+	// RFC 3962 appendix B, first vector: the AES keys are DK(PBKDF2 output, "kerberos")
+	assert.Equal(mustDecodeHex("42263c6e89f4fc28b8df68ee09799f15"),
+		aesDK(mustDecodeHex("cdedb5281bb2f801565a1122b2563515"), []byte("kerberos")))
+	assert.Equal(mustDecodeHex("fe697b52bc0d3ce14432ba036a92e65bbb52280990a2fa27883998d72af30161"),
+		aesDK(mustDecodeHex("cdedb5281bb2f801565a1122b25635150ad1f7a04bb9f3a333ecc0e2e1f70837"), []byte("kerberos")))
+}
+
+func TestPseudoRandom(t *testing.T) {
+	assert := NewAssert(t)
+	if !hasSymbol("gss_pseudo_random") {
+		t.Skip("gss_pseudo_random is not available in this GSSAPI library")
+	}
+
+	initiator, acceptor := establishContexts(t, g.ContextFlagMutual)
+
+	vectors := []struct {
+		name   string
+		key    PRFKey
+		in     []byte
+		outLen int
+	}{
+		{"full-empty", PRFKeyFull, []byte{}, 16},
+		{"full-short", PRFKeyFull, []byte("go-gssapi-c"), 32},
+		{"full-long", PRFKeyFull, []byte("go-gssapi-c"), 300},
+		{"partial-short", PRFKeyPartial, []byte("go-gssapi-c"), 32},
+		{"partial-long", PRFKeyPartial, []byte("go-gssapi-c"), 300},
+	}
+
+	outputs := map[string][]byte{}
+	for _, v := range vectors {
+		t.Run(v.name, func(t *testing.T) {
+			assert := NewAssert(t)
+
+			initOut, err := initiator.PseudoRandom(v.key, v.in, v.outLen)
+			assert.NoErrorFatal(err)
+			assert.Len(initOut, v.outLen)
+
+			accOut, err := acceptor.PseudoRandom(v.key, v.in, v.outLen)
+			assert.NoErrorFatal(err)
+			assert.Equal(initOut, accOut)
+
+			// the same input gives the same output
+			again, err := initiator.PseudoRandom(v.key, v.in, v.outLen)
+			assert.NoErrorFatal(err)
+			assert.Equal(initOut, again)
+
+			outputs[v.name] = initOut
+		})
+	}
+
+	// PRF+ output is a prefix of a longer request with the same input
+	for _, key := range []string{"full", "partial"} {
+		short, long := outputs[key+"-short"], outputs[key+"-long"]
+		if assert.Len(short, 32) && assert.Greater(len(long), 32) {
+			assert.Equal(short, long[:32])
+		}
+	}
+
+	// ..and different inputs give different outputs
+	other, err := initiator.PseudoRandom(PRFKeyFull, []byte("go-gssapi"), 32)
+	assert.NoError(err)
+	assert.NotEqual(outputs["full-short"], other)
+
+	// the output is PRF+ keyed with the session key, which is the full key
+	if !hasSymbol("gss_inquire_sec_context_by_oid") {
+		t.Log("skipping the PRF+ known answer test because the session key cannot be inquired")
+		return
+	}
+	key, err := acceptor.SessionKey()
+	assert.NoErrorFatal(err)
+	if key.Enctype != EnctypeAes128CtsHmacSha196 && key.Enctype != EnctypeAes256CtsHmacSha196 {
+		t.Logf("skipping the PRF+ known answer test for session key enctype %d", key.Enctype)
+		return
+	}
+	for _, v := range vectors {
+		if v.key == PRFKeyFull {
+			assert.Equal(aesPRFPlus(key.Key, v.in, v.outLen), outputs[v.name], v.name)
+		}
+	}
+}
+
+func TestPseudoRandomBadArgs(t *testing.T) {
+	assert := NewAssert(t)
+
+	c := &SecContext{}
+	_, err := c.PseudoRandom(PRFKey(5), []byte("x"), 16)
+	assert.ErrorIs(err, g.ErrFailure)
+
+	_, err = c.PseudoRandom(PRFKeyFull, []byte("x"), 0)
+	assert.ErrorIs(err, g.ErrFailure)
+}
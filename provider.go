@@ -26,7 +26,14 @@ type provider struct {
 	name string
 }
 
+// New returns the provider.  When built with the usedlopen build tag it first opens the GSSAPI
+// library named by the GOGSSAPI_LIBRARY environment variable, or the first of DefaultLibraries
+// that can be opened, unless a library has already been opened.
 func New() (g.Provider, error) {
+	if err := loadDefaultLibrary(); err != nil {
+		return nil, err
+	}
+
 	return &provider{
 		name: LIBID,
 	}, nil
@@ -45,7 +52,7 @@ func (p provider) Name() string {
 var ErrTooLarge = errors.New("the GSSAPI-C bindings only support up to 32 bit messages")
 
 func isHeimdal() bool {
-	return C.IS_HEIMDAL == 1
+	return C.is_heimdal() == 1
 }
 
 func isHeimdalBefore7() bool {
@@ -0,0 +1,194 @@
+//go:build usedlopen && unix && !darwin
+
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+// With the usedlopen build tag the provider is not linked to a GSSAPI library.  Instead
+// the library is opened with dlopen when the provider is created and every GSSAPI function
+// is called through a pointer, so that one binary can use MIT or Heimdal Kerberos depending
+// on what is installed on the host.  The GSSAPI headers are still needed to build; MIT and
+// Heimdal agree on the layout of the structures used by the provider.
+
+//#cgo CFLAGS: -DGOGSSAPI_DLOPEN
+/*
+#include "gss.h"
+#include <dlfcn.h>
+
+static void *_gogssapi_dlopen(const char *path, const char **errmsg) {
+	void *handle = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+	if( handle == NULL ) {
+		*errmsg = dlerror();
+	}
+	return handle;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"unsafe"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// LibraryEnv is the environment variable that New uses to find the GSSAPI library
+const LibraryEnv = "GOGSSAPI_LIBRARY"
+
+// DefaultLibraries are the GSSAPI libraries that New tries in order if LibraryEnv is not set:
+// MIT Kerberos and then Heimdal.
+var DefaultLibraries = []string{
+	"libgssapi_krb5.so.2",
+	"libgssapi.so.3",
+}
+
+// ErrLibraryLoaded is returned when asked to use a GSSAPI library after a different one has
+// been opened.  The library can only be chosen once per process.
+var ErrLibraryLoaded = errors.New("a different GSSAPI library has already been loaded")
+
+// The GSSAPI functions called by the provider.  Each has a declaration in gss_dlopen.h and a
+// definition in gss.c
+var coreSymbols = symbolMap{
+	"gss_accept_sec_context":     &C._gogssapi_dl_gss_accept_sec_context,
+	"gss_acquire_cred":           &C._gogssapi_dl_gss_acquire_cred,
+	"gss_add_cred":               &C._gogssapi_dl_gss_add_cred,
+	"gss_add_oid_set_member":     &C._gogssapi_dl_gss_add_oid_set_member,
+	"gss_canonicalize_name":      &C._gogssapi_dl_gss_canonicalize_name,
+	"gss_compare_name":           &C._gogssapi_dl_gss_compare_name,
+	"gss_context_time":           &C._gogssapi_dl_gss_context_time,
+	"gss_create_empty_oid_set":   &C._gogssapi_dl_gss_create_empty_oid_set,
+	"gss_delete_sec_context":     &C._gogssapi_dl_gss_delete_sec_context,
+	"gss_display_name":           &C._gogssapi_dl_gss_display_name,
+	"gss_display_status":         &C._gogssapi_dl_gss_display_status,
+	"gss_duplicate_name":         &C._gogssapi_dl_gss_duplicate_name,
+	"gss_export_name":            &C._gogssapi_dl_gss_export_name,
+	"gss_export_sec_context":     &C._gogssapi_dl_gss_export_sec_context,
+	"gss_get_mic":                &C._gogssapi_dl_gss_get_mic,
+	"gss_import_name":            &C._gogssapi_dl_gss_import_name,
+	"gss_import_sec_context":     &C._gogssapi_dl_gss_import_sec_context,
+	"gss_indicate_mechs":         &C._gogssapi_dl_gss_indicate_mechs,
+	"gss_init_sec_context":       &C._gogssapi_dl_gss_init_sec_context,
+	"gss_inquire_context":        &C._gogssapi_dl_gss_inquire_context,
+	"gss_inquire_cred":           &C._gogssapi_dl_gss_inquire_cred,
+	"gss_inquire_cred_by_mech":   &C._gogssapi_dl_gss_inquire_cred_by_mech,
+	"gss_inquire_mechs_for_name": &C._gogssapi_dl_gss_inquire_mechs_for_name,
+	"gss_inquire_names_for_mech": &C._gogssapi_dl_gss_inquire_names_for_mech,
+	"gss_process_context_token":  &C._gogssapi_dl_gss_process_context_token,
+	"gss_release_buffer":         &C._gogssapi_dl_gss_release_buffer,
+	"gss_release_buffer_set":     &C._gogssapi_dl_gss_release_buffer_set,
+	"gss_release_cred":           &C._gogssapi_dl_gss_release_cred,
+	"gss_release_name":           &C._gogssapi_dl_gss_release_name,
+	"gss_release_oid_set":        &C._gogssapi_dl_gss_release_oid_set,
+	"gss_unwrap":                 &C._gogssapi_dl_gss_unwrap,
+	"gss_verify_mic":             &C._gogssapi_dl_gss_verify_mic,
+	"gss_wrap":                   &C._gogssapi_dl_gss_wrap,
+	"gss_wrap_size_limit":        &C._gogssapi_dl_gss_wrap_size_limit,
+}
+
+var (
+	libraryMutex sync.Mutex
+	libraryPath  string
+)
+
+// NewFromLibrary opens the GSSAPI library at path, for example libgssapi_krb5.so.2 for MIT
+// Kerberos or libgssapi.so.3 for Heimdal, and returns the provider.  The path is passed to
+// dlopen so it is searched for in the usual places if it does not contain a slash.  It is
+// only available when built with the usedlopen build tag.
+func NewFromLibrary(path string) (g.Provider, error) {
+	if err := loadLibrary(path); err != nil {
+		return nil, err
+	}
+
+	return &provider{
+		name: LIBID,
+	}, nil
+}
+
+// LibraryPath returns the path of the GSSAPI library that has been opened, or an empty string
+func LibraryPath() string {
+	libraryMutex.Lock()
+	defer libraryMutex.Unlock()
+
+	return libraryPath
+}
+
+func loadDefaultLibrary() error {
+	if LibraryPath() != "" {
+		return nil
+	}
+
+	if path := os.Getenv(LibraryEnv); path != "" {
+		return loadLibrary(path)
+	}
+
+	var errs []error
+	for _, path := range DefaultLibraries {
+		err := loadLibrary(path)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+func loadLibrary(path string) error {
+	libraryMutex.Lock()
+	defer libraryMutex.Unlock()
+
+	if libraryPath != "" {
+		if path == libraryPath {
+			return nil
+		}
+		return fmt.Errorf("%w: %s", ErrLibraryLoaded, libraryPath)
+	}
+
+	cPath := C.CString(path) // allocated by C; released by *1
+	// *1  release the path
+	defer C.free(unsafe.Pointer(cPath))
+
+	var cErr *C.char
+	handle := C._gogssapi_dlopen(cPath, &cErr)
+	if handle == nil {
+		return fmt.Errorf("opening GSSAPI library: %s", C.GoString(cErr))
+	}
+
+	// check that this is a GSSAPI library before setting anything
+	funcPtrs := make(map[string]unsafe.Pointer, len(coreSymbols))
+	for sym := range coreSymbols {
+		funcPtr := lookupSymbol(handle, sym)
+		if funcPtr == nil {
+			C.dlclose(handle)
+			return fmt.Errorf("%s is not a usable GSSAPI library: %s is missing", path, sym)
+		}
+		funcPtrs[sym] = funcPtr
+	}
+
+	for sym, ptr := range coreSymbols {
+		*ptr = (*[0]byte)(funcPtrs[sym])
+	}
+
+	// Heimdal's krb5 mechanism names its extensions gsskrb5_*, MIT uses gss_krb5_*
+	if lookupSymbol(handle, "gsskrb5_register_acceptor_identity") != nil {
+		C._gogssapi_dl_is_heimdal = 1
+	}
+
+	// GSS_C_CHANNEL_BOUND_FLAG in the headers says nothing about the library that was opened.
+	// MIT added the flag in 1.19 along with the GSS_KRB5_NT_X509_CERT name type, which the
+	// library exports
+	if C._gogssapi_dl_is_heimdal == 0 && lookupSymbol(handle, "GSS_KRB5_NT_X509_CERT") != nil {
+		C._gogssapi_dl_has_channel_bound = 1
+	}
+
+	useLibrary(handle)
+	libraryPath = path
+
+	// the library may not be thread safe
+	updateSerializeCalls()
+
+	return nil
+}
@@ -0,0 +1,31 @@
+//go:build usedlopen && unix && !darwin
+
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"testing"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+func TestNewFromLibrary(t *testing.T) {
+	assert := NewAssert(t)
+
+	// the test assets have already opened the default library
+	path := LibraryPath()
+	assert.NotEmpty(path)
+
+	p, err := NewFromLibrary(path)
+	assert.NoErrorFatal(err)
+	assert.IsType(&provider{}, p)
+
+	_, err = NewFromLibrary("libgssapi-no-such-library.so")
+	assert.ErrorIs(err, ErrLibraryLoaded)
+
+	// the library is usable through the function pointers
+	mechs, err := p.IndicateMechs()
+	assert.NoError(err)
+	assert.Contains(mechs, g.GSS_MECH_KRB5)
+}
@@ -1,4 +1,4 @@
-//go:build freebsd
+//go:build freebsd && !usedlopen
 
 // SPDX-License-Identifier: Apache-2.0
 
@@ -0,0 +1,10 @@
+//go:build !(usedlopen && unix && !darwin)
+
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+// The GSSAPI library is linked at build time, so there is nothing to load
+func loadDefaultLibrary() error {
+	return nil
+}
@@ -1,4 +1,4 @@
-//go:build openbsd
+//go:build openbsd && !usedlopen
 
 // SPDX-License-Identifier: Apache-2.0
 
@@ -1,6 +1,6 @@
 // SPDX-License-Identifier: Apache-2.0
 
-//go:build unix && !darwin && !freebsd && !openbsd
+//go:build unix && !darwin && !freebsd && !openbsd && !usedlopen
 
 package gssapi
 
@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// Mechanisms and name types returned by the library that go-gssapi does not know about are
+// represented by Mech and NameType instead of being dropped.  Registering an OID gives it a
+// name; unregistered OIDs are named by their dotted string and reported to the hook set with
+// SetUnknownOidHook.
+
+// Mech is a GSSAPI mechanism that is not one of go-gssapi's well known mechanisms.  It can be
+// passed back to the provider wherever a g.GssMech is accepted.  Mech values are comparable.
+type Mech struct {
+	oid  string
+	name string
+}
+
+func (m Mech) Oid() g.Oid {
+	return g.Oid(m.oid)
+}
+
+func (m Mech) OidString() string {
+	s, err := oid2String(g.Oid(m.oid))
+	if err != nil {
+		return fmt.Sprintf("%x", m.oid)
+	}
+	return s
+}
+
+func (m Mech) String() string {
+	return m.name
+}
+
+// NameType is a GSSAPI name type that is not one of go-gssapi's well known name types.  It can
+// be passed back to the provider wherever a g.GssNameType is accepted.  NameType values are
+// comparable.
+type NameType struct {
+	oid  string
+	name string
+}
+
+func (nt NameType) Oid() g.Oid {
+	return g.Oid(nt.oid)
+}
+
+func (nt NameType) OidString() string {
+	s, err := oid2String(g.Oid(nt.oid))
+	if err != nil {
+		return fmt.Sprintf("%x", nt.oid)
+	}
+	return s
+}
+
+func (nt NameType) String() string {
+	return nt.name
+}
+
+// OidKind says whether an OID reported to the unknown OID hook is a mechanism or a name type
+type OidKind int
+
+const (
+	OidKindMech OidKind = iota
+	OidKindNameType
+)
+
+func (k OidKind) String() string {
+	if k == OidKindNameType {
+		return "name type"
+	}
+	return "mechanism"
+}
+
+var (
+	registryMutex    sync.RWMutex
+	registeredMechs  = make(map[string]Mech)
+	registeredNTs    = make(map[string]NameType)
+	unknownOidHook   func(kind OidKind, oid string)
+	errEmptyMechOid  = fmt.Errorf("%w: empty OID", g.ErrBadMech)
+	errEmptyNameType = fmt.Errorf("%w: empty OID", g.ErrBadNameType)
+)
+
+// Mechanisms used with the provider that go-gssapi does not define
+var (
+	// Microsoft NTLM, for example from gss-ntlmssp
+	GSS_MECH_NTLMSSP = MustRegisterMech("1.3.6.1.4.1.311.2.2.10", "GSS_MECH_NTLMSSP")
+	// The SPNEGO extended negotiation (NegoEx) pseudo-mechanism
+	GSS_MECH_NEGOEX = MustRegisterMech("1.3.6.1.4.1.311.2.2.30", "GSS_MECH_NEGOEX")
+)
+
+// RegisterMech names the mechanism with the dotted OID, so that the provider returns it under
+// that name.  OIDs of mechanisms known to go-gssapi cannot be registered.
+func RegisterMech(oid string, name string) (Mech, error) {
+	binOid, err := string2Oid(oid)
+	if err != nil {
+		return Mech{}, fmt.Errorf("%w: %w", g.ErrBadMech, err)
+	}
+	if _, err := g.MechFromOid(binOid); err == nil {
+		return Mech{}, fmt.Errorf("%w: %s is already known to go-gssapi", g.ErrBadMech, oid)
+	}
+
+	mech := Mech{oid: string(binOid), name: name}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registeredMechs[mech.oid] = mech
+
+	return mech, nil
+}
+
+// MustRegisterMech is like RegisterMech but panics on error.  It is intended for package
+// variables.
+func MustRegisterMech(oid string, name string) Mech {
+	mech, err := RegisterMech(oid, name)
+	if err != nil {
+		panic(err)
+	}
+	return mech
+}
+
+// RegisterNameType names the name type with the dotted OID, so that the provider returns it
+// under that name.  OIDs of name types known to go-gssapi cannot be registered.
+func RegisterNameType(oid string, name string) (NameType, error) {
+	binOid, err := string2Oid(oid)
+	if err != nil {
+		return NameType{}, fmt.Errorf("%w: %w", g.ErrBadNameType, err)
+	}
+	if _, err := g.NameTypeFromOid(binOid); err == nil {
+		return NameType{}, fmt.Errorf("%w: %s is already known to go-gssapi", g.ErrBadNameType, oid)
+	}
+
+	nt := NameType{oid: string(binOid), name: name}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registeredNTs[nt.oid] = nt
+
+	return nt, nil
+}
+
+// SetUnknownOidHook sets a function that is called with the dotted OID whenever the library
+// returns a mechanism or name type that is neither known to go-gssapi nor registered.  The OID
+// is still returned to the caller, as a Mech or NameType named after the OID.  Pass nil to
+// remove the hook.
+func SetUnknownOidHook(hook func(kind OidKind, oid string)) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	unknownOidHook = hook
+}
+
+// mechFromOid returns the go-gssapi mechanism for the OID, the registered mechanism, or else
+// an opaque mechanism named after the OID.  Only an empty OID is an error.
+func mechFromOid(oid g.Oid) (g.GssMech, error) {
+	if len(oid) == 0 {
+		return nil, errEmptyMechOid
+	}
+
+	mech, err := g.MechFromOid(oid)
+	switch {
+	case err == nil:
+		return mech, nil
+	case !errors.Is(err, g.ErrBadMech):
+		return nil, err
+	}
+
+	registryMutex.RLock()
+	regMech, ok := registeredMechs[string(oid)]
+	hook := unknownOidHook
+	registryMutex.RUnlock()
+
+	if ok {
+		return regMech, nil
+	}
+
+	unknown := Mech{oid: string(oid)}
+	unknown.name = unknown.OidString()
+	if hook != nil {
+		hook(OidKindMech, unknown.name)
+	}
+
+	return unknown, nil
+}
+
+// nameTypeFromOid is the name type equivalent of mechFromOid
+func nameTypeFromOid(oid g.Oid) (g.GssNameType, error) {
+	if len(oid) == 0 {
+		return nil, errEmptyNameType
+	}
+
+	nt, err := g.NameTypeFromOid(oid)
+	switch {
+	case err == nil:
+		return nt, nil
+	case !errors.Is(err, g.ErrBadNameType):
+		return nil, err
+	}
+
+	registryMutex.RLock()
+	regNT, ok := registeredNTs[string(oid)]
+	hook := unknownOidHook
+	registryMutex.RUnlock()
+
+	if ok {
+		return regNT, nil
+	}
+
+	unknown := NameType{oid: string(oid)}
+	unknown.name = unknown.OidString()
+	if hook != nil {
+		hook(OidKindNameType, unknown.name)
+	}
+
+	return unknown, nil
+}
@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"slices"
+	"testing"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+func TestString2Oid(t *testing.T) {
+	assert := NewAssert(t)
+
+	oid, err := string2Oid("1.2.840.113554.1.2.2")
+	assert.NoError(err)
+	assert.Equal(g.GSS_MECH_KRB5.Oid(), oid)
+
+	s, err := oid2String(oid)
+	assert.NoError(err)
+	assert.Equal("1.2.840.113554.1.2.2", s)
+
+	for _, bad := range []string{"", "1.2.x", "1..2", "1.-2"} {
+		_, err = string2Oid(bad)
+		assert.Error(err, bad)
+	}
+}
+
+func TestRegisterMech(t *testing.T) {
+	assert := NewAssert(t)
+
+	assert.Equal("GSS_MECH_NTLMSSP", GSS_MECH_NTLMSSP.String())
+	assert.Equal("1.3.6.1.4.1.311.2.2.10", GSS_MECH_NTLMSSP.OidString())
+
+	mech, err := RegisterMech("1.3.6.1.4.1.99999.1", "TEST_MECH")
+	assert.NoErrorFatal(err)
+	assert.Equal("TEST_MECH", mech.String())
+	assert.Equal("1.3.6.1.4.1.99999.1", mech.OidString())
+
+	// the provider returns the registered value, which compares equal
+	found, err := mechFromOid(mech.Oid())
+	assert.NoError(err)
+	assert.Equal(g.GssMech(mech), found)
+	assert.True(slices.Contains([]g.GssMech{g.GSS_MECH_KRB5, found}, g.GssMech(mech)))
+
+	_, err = RegisterMech(g.GSS_MECH_KRB5.OidString(), "KRB5")
+	assert.ErrorIs(err, g.ErrBadMech)
+
+	_, err = RegisterMech("not an oid", "BAD")
+	assert.ErrorIs(err, g.ErrBadMech)
+}
+
+func TestRegisterNameType(t *testing.T) {
+	assert := NewAssert(t)
+
+	nt, err := RegisterNameType("1.3.6.1.4.1.99999.2", "TEST_NT")
+	assert.NoErrorFatal(err)
+
+	found, err := nameTypeFromOid(nt.Oid())
+	assert.NoError(err)
+	assert.Equal(g.GssNameType(nt), found)
+
+	_, err = RegisterNameType(g.GSS_NT_HOSTBASED_SERVICE.OidString(), "HBS")
+	assert.ErrorIs(err, g.ErrBadNameType)
+}
+
+func TestUnknownOids(t *testing.T) {
+	assert := NewAssert(t)
+
+	type report struct {
+		kind OidKind
+		oid  string
+	}
+	var reports []report
+	SetUnknownOidHook(func(kind OidKind, oid string) {
+		reports = append(reports, report{kind, oid})
+	})
+	defer SetUnknownOidHook(nil)
+
+	// well known OIDs come back as the go-gssapi values and are not reported
+	mech, err := mechFromOid(g.GSS_MECH_SPNEGO.Oid())
+	assert.NoError(err)
+	assert.Equal(g.GssMech(g.GSS_MECH_SPNEGO), mech)
+
+	nt, err := nameTypeFromOid(g.GSS_NT_USER_NAME.Oid())
+	assert.NoError(err)
+	assert.Equal(g.GssNameType(g.GSS_NT_USER_NAME), nt)
+	assert.Empty(reports)
+
+	// unknown OIDs are opaque values named after the OID
+	unknownOid, err := string2Oid("1.3.6.1.4.1.99999.3")
+	assert.NoErrorFatal(err)
+
+	mech, err = mechFromOid(unknownOid)
+	assert.NoError(err)
+	assert.Equal("1.3.6.1.4.1.99999.3", mech.String())
+	assert.Equal("1.3.6.1.4.1.99999.3", mech.OidString())
+	assert.Equal(unknownOid, mech.Oid())
+
+	nt, err = nameTypeFromOid(unknownOid)
+	assert.NoError(err)
+	assert.Equal("1.3.6.1.4.1.99999.3", nt.String())
+
+	assert.Equal([]report{
+		{OidKindMech, "1.3.6.1.4.1.99999.3"},
+		{OidKindNameType, "1.3.6.1.4.1.99999.3"},
+	}, reports)
+	assert.Equal("mechanism", OidKindMech.String())
+	assert.Equal("name type", OidKindNameType.String())
+
+	_, err = mechFromOid(nil)
+	assert.ErrorIs(err, g.ErrBadMech)
+	_, err = nameTypeFromOid(nil)
+	assert.ErrorIs(err, g.ErrBadNameType)
+}
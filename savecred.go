@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+/*
+#include "gss.h"
+#include <stdlib.h>
+#include <string.h>
+
+// The function pointers below are set to the actual function pointers in the library
+// by the init function using symbolMap.Apply()
+
+OM_uint32 (*__gogssapi_store_cred)(OM_uint32 *minor_status,
+								const gss_cred_id_t input_cred_handle,
+								gss_cred_usage_t cred_usage,
+								const gss_OID desired_mech,
+								OM_uint32 overwrite_cred,
+								OM_uint32 default_cred,
+								gss_OID_set *elements_stored,
+								gss_cred_usage_t *cred_usage_stored) = NULL;
+
+OM_uint32 (*__gogssapi_krb5_ccache_name)(OM_uint32 *minor_status, const char *name, const char **out_name) = NULL;
+
+// Store an initiator credential into the named ccache using gss_store_cred, for libraries
+// without gss_store_cred_into.  gss_store_cred writes to the default ccache, which is
+// overridden for the calling thread using gss_krb5_ccache_name and restored before returning.
+// This all happens in one call so that the Go runtime cannot move us to another thread
+// in the meantime.
+static OM_uint32 store_cred_ccache(OM_uint32 *minor_status, gss_cred_id_t cred, gss_OID mech, const char *ccname) {
+	const char *old_name = NULL;
+	OM_uint32 major = __gogssapi_krb5_ccache_name(minor_status, ccname, &old_name);
+	if( GSS_ERROR(major) ) {
+		return major;
+	}
+
+	// the library may reuse the storage of the old name
+	char *saved_name = NULL;
+	if( old_name != NULL ) {
+		saved_name = strdup(old_name);
+	}
+
+	major = __gogssapi_store_cred(minor_status, cred, GSS_C_INITIATE, mech, 1, 1, NULL, NULL);
+
+	OM_uint32 tmp_minor;
+	__gogssapi_krb5_ccache_name(&tmp_minor, saved_name, NULL);
+	free(saved_name);
+
+	return major;
+}
+
+OM_uint32 _gogssapi_store_cred_ccache(OM_uint32 *minor_status, gss_cred_id_t cred, gss_OID mech, const char *ccname) {
+	if( __gogssapi_store_cred == NULL || __gogssapi_krb5_ccache_name == NULL ) {
+		*minor_status = 0;
+		return GSS_S_UNAVAILABLE;
+	}
+	return _GOGSSAPI_LOCKED(store_cred_ccache(minor_status, cred, mech, ccname));
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// Map optional symbols from the GSSAPI library to the wrapper function pointers
+var storeCredSymbols = symbolMap{
+	"gss_store_cred":       &C.__gogssapi_store_cred,
+	"gss_krb5_ccache_name": &C.__gogssapi_krb5_ccache_name,
+}
+
+func init() {
+	storeCredSymbols.Apply()
+}
+
+// ErrNoDelegatedCredential is returned by SaveDelegatedCredential if the initiator did not delegate a credential
+var ErrNoDelegatedCredential = fmt.Errorf("%w: the initiator did not delegate a credential", g.ErrNoCred)
+
+type saveCredConfig struct {
+	mode     os.FileMode
+	uid, gid int
+}
+
+// SaveCredentialOption configures SaveDelegatedCredential
+type SaveCredentialOption func(*saveCredConfig)
+
+// WithCCacheMode sets the permissions of the credentials cache file.  The default is 0600.
+func WithCCacheMode(mode os.FileMode) SaveCredentialOption {
+	return func(c *saveCredConfig) {
+		c.mode = mode
+	}
+}
+
+// WithCCacheOwner sets the owner and group of the credentials cache file, so that it can be
+// used by a subprocess running as the user.  An ID of -1 leaves that ID unchanged.  Changing
+// the owner normally requires privileges.
+func WithCCacheOwner(uid, gid int) SaveCredentialOption {
+	return func(c *saveCredConfig) {
+		c.uid = uid
+		c.gid = gid
+	}
+}
+
+// SaveDelegatedCredential writes the credential delegated by the initiator of an accepted
+// context to a FILE credentials cache at path, replacing any existing file, and returns the
+// name of the cache.  Subprocesses can then use the credential by setting KRB5CCNAME to the
+// name.
+//
+// The cache is written to a temporary file which is given the requested mode and ownership
+// before being renamed to path, so the credential is never readable by anyone else.  The
+// credential is stored with gss_store_cred_into when the library supports the credential
+// store extension, and with gss_store_cred otherwise.
+func SaveDelegatedCredential(info g.SecContextInfoPartial, path string, opts ...SaveCredentialOption) (string, error) {
+	if info.DelegatedCredential == nil {
+		return "", ErrNoDelegatedCredential
+	}
+	cred, ok := info.DelegatedCredential.(*Credential)
+	if !ok {
+		return "", fmt.Errorf("bad credential type %T, %w", info.DelegatedCredential, g.ErrNoCred)
+	}
+
+	cfg := saveCredConfig{mode: 0600, uid: -1, gid: -1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	fh, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return "", err
+	}
+	tmpName := fh.Name()
+	if err = fh.Close(); err != nil {
+		return "", errors.Join(err, os.Remove(tmpName))
+	}
+
+	if err = saveCredFile(cred, tmpName, cfg); err != nil {
+		return "", errors.Join(err, os.Remove(tmpName))
+	}
+	if err = os.Rename(tmpName, path); err != nil {
+		return "", errors.Join(err, os.Remove(tmpName))
+	}
+
+	return "FILE:" + path, nil
+}
+
+func saveCredFile(cred *Credential, fileName string, cfg saveCredConfig) error {
+	ccname := "FILE:" + fileName
+
+	if hasSymbol("gss_store_cred_into") {
+		_, _, err := cred.StoreInto(g.GSS_MECH_KRB5, g.CredUsageInitiateOnly, true, false, g.WithCredStoreCCache(ccname))
+		if err != nil {
+			return err
+		}
+	} else if err := storeCredCCache(cred, ccname); err != nil {
+		return err
+	}
+
+	// the library may have replaced the file, so the permissions are applied afterwards
+	if err := os.Chmod(fileName, cfg.mode); err != nil {
+		return err
+	}
+	if cfg.uid != -1 || cfg.gid != -1 {
+		return os.Chown(fileName, cfg.uid, cfg.gid)
+	}
+
+	return nil
+}
+
+// storeCredCCache stores the Kerberos initiator credentials of cred into the ccache ccname
+// using gss_store_cred
+func storeCredCCache(cred *Credential, ccname string) error {
+	cMechOid, pinner := oid2Coid(g.GSS_MECH_KRB5.Oid(), nil)
+	defer pinner.Unpin()
+
+	cCCName := C.CString(ccname) // allocated by C; released by *1
+	// *1  release the name
+	defer C.free(unsafe.Pointer(cCCName))
+
+	var cMinor C.OM_uint32
+	cMajor := C._gogssapi_store_cred_ccache(&cMinor, cred.id, cMechOid, cCCName)
+	if cMajor != C.GSS_S_COMPLETE {
+		return makeMechStatus(cMajor, cMinor, g.GSS_MECH_KRB5)
+	}
+
+	return nil
+}
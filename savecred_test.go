@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+func TestSaveDelegatedCredential(t *testing.T) {
+	if !hasSymbol("gss_store_cred_into") && !(hasSymbol("gss_store_cred") && hasSymbol("gss_krb5_ccache_name")) {
+		t.Log("skipping save credential test because the library cannot store credentials")
+		t.SkipNow()
+	}
+
+	assert := NewAssert(t)
+
+	ta.useAsset(t, testNoKeytab|testCredCache)
+
+	// stand in for a delegated credential
+	cred, err := ta.lib.AcquireCredential(nil, nil, g.CredUsageInitiateOnly, nil)
+	assert.NoErrorFatal(err)
+	defer cred.Release() //nolint:errcheck
+
+	info := g.SecContextInfoPartial{Flags: g.ContextFlagDeleg, DelegatedCredential: cred}
+
+	path := filepath.Join(t.TempDir(), "krb5cc_deleg")
+	ccname, err := SaveDelegatedCredential(info, path, WithCCacheMode(0640))
+	assert.NoErrorFatal(err)
+	assert.Equal("FILE:"+path, ccname)
+
+	st, err := os.Stat(path)
+	assert.NoErrorFatal(err)
+	assert.Equal(os.FileMode(0640), st.Mode().Perm())
+
+	cc, err := ReadCCache(ccname)
+	assert.NoErrorFatal(err)
+	assert.Equal("robot@GOLANG-AUTH.IO", cc.DefaultPrincipal.String())
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(err)
+	assert.Len(entries, 1)
+
+	// saving again replaces the cache
+	_, err = SaveDelegatedCredential(info, path)
+	assert.NoError(err)
+	st, err = os.Stat(path)
+	assert.NoErrorFatal(err)
+	assert.Equal(os.FileMode(0600), st.Mode().Perm())
+
+	_, err = SaveDelegatedCredential(g.SecContextInfoPartial{}, path)
+	assert.ErrorIs(err, ErrNoDelegatedCredential)
+	assert.ErrorIs(err, g.ErrNoCred)
+}
+
+func TestStoreCredCCache(t *testing.T) {
+	if !hasSymbol("gss_store_cred") || !hasSymbol("gss_krb5_ccache_name") {
+		t.Log("skipping gss_store_cred test because the library does not support it")
+		t.SkipNow()
+	}
+
+	assert := NewAssert(t)
+
+	ta.useAsset(t, testNoKeytab|testCredCache)
+
+	cred, err := ta.lib.AcquireCredential(nil, nil, g.CredUsageInitiateOnly, nil)
+	assert.NoErrorFatal(err)
+	defer cred.Release() //nolint:errcheck
+
+	before, err := os.Stat(ta.ccName)
+	assert.NoErrorFatal(err)
+
+	path := filepath.Join(t.TempDir(), "krb5cc")
+	err = storeCredCCache(cred.(*Credential), "FILE:"+path)
+	assert.NoErrorFatal(err)
+
+	cc, err := ReadCCache(path)
+	assert.NoErrorFatal(err)
+	assert.Equal("robot@GOLANG-AUTH.IO", cc.DefaultPrincipal.String())
+
+	// the default cache is left alone
+	after, err := os.Stat(ta.ccName)
+	assert.NoErrorFatal(err)
+	assert.Equal(before.ModTime(), after.ModTime())
+}
@@ -8,6 +8,8 @@ package gssapi
 import "C"
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -32,6 +34,9 @@ type SecContext struct {
 
 	initOptions   *g.InitSecContextOptions
 	acceptOptions *g.AcceptSecContextOptions
+
+	// set when ContinueContext gave up on a call; the context is then unusable
+	abandoned bool
 }
 
 func newSecContext(isInitiator bool) SecContext {
@@ -89,8 +94,10 @@ func (provider) AcceptSecContext(opts ...g.AcceptSecContextOption) (g.SecContext
 	return &ctx, nil
 }
 
-// initSecContext() performs the GSSAPI context initialization using paramers supplied to InitSecContext()
-func (c *SecContext) initSecContext() ([]byte, g.SecContextInfoPartial, error) {
+// initSecContext() calls gss_init_sec_context using the parameters supplied to InitSecContext().
+// The same parameters are used for every call: multi-round mechanisms such as IAKERB only build
+// the AP-REQ, with its flags and channel bindings, after proxying several KDC exchanges.
+func (c *SecContext) initSecContext(inputToken []byte) ([]byte, g.SecContextInfoPartial, error) {
 	mech := g.Oid{} // the empty OID is mapped to GSS_C_NO_OID by oid2Coid
 
 	// use a specific mech if requested in call to InitSecContext
@@ -118,11 +125,17 @@ func (c *SecContext) initSecContext() ([]byte, g.SecContextInfoPartial, error) {
 		cChBindings, _ = mkChannelBindings(c.initOptions.ChannelBinding, pinner)
 	}
 
+	// there is no input token on the first call
+	var cpInputToken C.gss_buffer_t = nil
+	if c.id != C.GSS_C_NO_CONTEXT {
+		cInputToken, _ := bytesToCBuffer(inputToken, pinner)
+		cpInputToken = &cInputToken
+	}
+
 	var cMinor, cRetFlags, cTimeRec C.OM_uint32
-	var cGssCtxID C.gss_ctx_id_t = C.GSS_C_NO_CONTEXT
 	var cOutToken C.gss_buffer_desc = C.gss_empty_buffer // cOutToken.value allocated by GSSAPI; released by *1
 	var cActualMech C.gss_OID = C.GSS_C_NO_OID           // DO NOT FREE
-	cMajor := C.gss_init_sec_context(&cMinor, cGssCred, &cGssCtxID, cGssTargetName, cMechOid, C.OM_uint32(c.initOptions.Flags), C.OM_uint32(c.initOptions.Lifetime.Seconds()), cChBindings, nil, &cActualMech, &cOutToken, &cRetFlags, &cTimeRec)
+	cMajor := C.gss_init_sec_context(&cMinor, cGssCred, &c.id, cGssTargetName, cMechOid, C.OM_uint32(c.initOptions.Flags), C.OM_uint32(c.initOptions.Lifetime.Seconds()), cChBindings, cpInputToken, &cActualMech, &cOutToken, &cRetFlags, &cTimeRec)
 
 	// *1  release GSSAPI allocated buffer
 	defer C.gss_release_buffer(&cMinor, &cOutToken)
@@ -135,11 +148,10 @@ func (c *SecContext) initSecContext() ([]byte, g.SecContextInfoPartial, error) {
 	}
 
 	if cMajor != C.GSS_S_COMPLETE && (cMajor&C.GSS_S_CONTINUE_NEEDED) == 0 {
-		return outToken, g.SecContextInfoPartial{}, makeMechStatus(cMajor, cMinor, c.initOptions.Mech)
+		return outToken, g.SecContextInfoPartial{}, makeMechStatus(cMajor, cMinor, c.actualMech(cActualMech))
 	}
 
 	c.continueNeeded = (cMajor & C.GSS_S_CONTINUE_NEEDED) > 0
-	c.id = cGssCtxID
 
 	ctxFlags, protFlag, transFlag := splitFlags(cRetFlags)
 
@@ -156,11 +168,13 @@ func (c *SecContext) initSecContext() ([]byte, g.SecContextInfoPartial, error) {
 		DelegatedCredential: nil,
 	}
 	if cActualMech != C.GSS_C_NO_OID {
-		mech, err := g.MechFromOid(oidFromGssOid(cActualMech))
+		mech, err := mechFromOid(oidFromGssOid(cActualMech))
 		if err != nil {
-			return outToken, info, fmt.Errorf("unknown mech returned from gss_init_sec_context: %w", g.ErrBadMech)
+			// any OID is returned as a mechanism, so this is an empty one
+			return outToken, info, fmt.Errorf("gss_init_sec_context returned an invalid mech: %w", err)
 		}
 		info.Mech = mech
+		c.mech = mech
 	}
 
 	return outToken, info, nil
@@ -174,6 +188,23 @@ func splitFlags(flags C.OM_uint32) (g.ContextFlag, bool, bool) {
 	return g.ContextFlag(flags), protFlag > 0, transFlag > 0
 }
 
+// actualMech returns the mechanism in the mech_type output of gss_init_sec_context or
+// gss_accept_sec_context, or the one already known for the context if there isn't one
+func (c *SecContext) actualMech(cActualMech C.gss_OID) g.GssMech {
+	if cActualMech == C.GSS_C_NO_OID {
+		return c.mech
+	}
+
+	mech, err := mechFromOid(oidFromGssOid(cActualMech))
+	if err != nil {
+		return c.mech
+	}
+
+	return mech
+}
+
+// acceptSecContext() calls gss_accept_sec_context using the parameters supplied to
+// AcceptSecContext(), which are needed on every call for the same reason as for initSecContext()
 func (c *SecContext) acceptSecContext(inputToken []byte) ([]byte, g.SecContextInfoPartial, error) {
 	// get the C cred ID and name
 	var cGssAcceptorCred C.gss_cred_id_t = C.GSS_C_NO_CREDENTIAL
@@ -194,15 +225,24 @@ func (c *SecContext) acceptSecContext(inputToken []byte) ([]byte, g.SecContextIn
 		cChBindings, pinner = mkChannelBindings(c.acceptOptions.ChannelBinding, pinner)
 	}
 
-	var cMinor, cRetFlags, cTimeRec C.OM_uint32
+	// Ask for the initiator name and delegated credential if we don't already have them
 	var cInitiatorName C.gss_name_t = C.GSS_C_NO_NAME // allocated by GSSAPI; released by *2 on error
-	var cGssCtxID C.gss_ctx_id_t = C.GSS_C_NO_CONTEXT
+	var cpInitiatorName *C.gss_name_t = nil
+	if c.initiatorName == nil {
+		cpInitiatorName = &cInitiatorName
+	}
+	var cGssDelegCred C.gss_cred_id_t = C.GSS_C_NO_CREDENTIAL // allocated by GSSAPI; released by *3 on error
+	var cpGssDelegCred *C.gss_cred_id_t = nil
+	if c.delegCred == nil {
+		cpGssDelegCred = &cGssDelegCred
+	}
+
+	var cMinor, cRetFlags, cTimeRec C.OM_uint32
 	var cOutToken C.gss_buffer_desc = C.gss_empty_buffer // cOutToken.value allocated by GSSAPI; released by *1
 	var cActualMech C.gss_OID = C.GSS_C_NO_OID
-	var cGssDelegCred C.gss_cred_id_t = C.GSS_C_NO_CREDENTIAL // allocated by GSSAPI; released by *3 on error
 	cInputToken, _ := bytesToCBuffer(inputToken, pinner)
 
-	cMajor := C.gss_accept_sec_context(&cMinor, &cGssCtxID, cGssAcceptorCred, &cInputToken, cChBindings, &cInitiatorName, &cActualMech, &cOutToken, &cRetFlags, &cTimeRec, &cGssDelegCred)
+	cMajor := C.gss_accept_sec_context(&cMinor, &c.id, cGssAcceptorCred, &cInputToken, cChBindings, cpInitiatorName, &cActualMech, &cOutToken, &cRetFlags, &cTimeRec, cpGssDelegCred)
 
 	// *1  release GSSAPI allocated buffer
 	defer C.gss_release_buffer(&cMinor, &cOutToken)
@@ -217,12 +257,11 @@ func (c *SecContext) acceptSecContext(inputToken []byte) ([]byte, g.SecContextIn
 		var errs []error
 		errs = append(errs, gssRelease(gssReleaseCred, &cGssDelegCred))  // *3   release delegated credential
 		errs = append(errs, gssRelease(gssReleaseName, &cInitiatorName)) // *2   release initiator name
-		errs = append(errs, makeStatus(cMajor, cMinor))
+		errs = append(errs, makeMechStatus(cMajor, cMinor, c.actualMech(cActualMech)))
 		return outToken, g.SecContextInfoPartial{}, errors.Join(errs...)
 	}
 
 	c.continueNeeded = (cMajor & C.GSS_S_CONTINUE_NEEDED) > 0
-	c.id = cGssCtxID
 	// Some mechs (e.g. SPNEGO mid-handshake) defer setting src_name until the
 	// context is fully established; leave c.initiatorName nil so the next
 	// Continue() round will re-request it.
@@ -247,11 +286,13 @@ func (c *SecContext) acceptSecContext(inputToken []byte) ([]byte, g.SecContextIn
 		DelegatedCredential: c.delegCred,
 	}
 	if cActualMech != C.GSS_C_NO_OID {
-		mech, err := g.MechFromOid(oidFromGssOid(cActualMech))
+		mech, err := mechFromOid(oidFromGssOid(cActualMech))
 		if err != nil {
-			return outToken, info, fmt.Errorf("unknown mech returned from gss_accept_sec_context: %w", g.ErrBadMech)
+			// any OID is returned as a mechanism, so this is an empty one
+			return outToken, info, fmt.Errorf("gss_accept_sec_context returned an invalid mech: %w", err)
 		}
 		info.Mech = mech
+		c.mech = mech
 	}
 
 	return outToken, info, nil
@@ -270,105 +311,79 @@ func (provider) ImportSecContext(token []byte) (g.SecContext, error) {
 	}
 
 	return &SecContext{
-		id: cGssCtxID,
+		id:            cGssCtxID,
+		initOptions:   &g.InitSecContextOptions{},
+		acceptOptions: &g.AcceptSecContextOptions{},
 	}, nil
 
 }
 
+// Continue performs the next step of context establishment.  Mechanisms may take any number
+// of rounds: IAKERB initiators, for example, send their KDC exchanges through the acceptor
+// before the AP-REQ.  Tokens should be passed between the peers until ContinueNeeded
+// returns false on both sides.
 func (c *SecContext) Continue(inputToken []byte) ([]byte, g.SecContextInfoPartial, error) {
-	// if the context is not yet initialized then do that..
-	if c.id == nil {
-		if c.isInitiator {
-			return c.initSecContext()
-		} else {
-			return c.acceptSecContext(inputToken)
-		}
+	if c.abandoned {
+		return nil, g.SecContextInfoPartial{}, errAbandoned
 	}
 
-	// otherwise continue establishing the context..
-	//
-	var cMajor, cMinor, cRetFlags, cTimeRec C.OM_uint32
-	var cOutToken C.gss_buffer_desc = C.gss_empty_buffer // cOutToken.value allocated by GSSAPI; released by *1
-	var cActualMech C.gss_OID = C.GSS_C_NO_OID
-	var cInitiatorName C.gss_name_t = C.GSS_C_NO_NAME         // allocated by GSSAPI; released by *2 on error
-	var cGssDelegCred C.gss_cred_id_t = C.GSS_C_NO_CREDENTIAL // allocated by GSSAPI; released by *3 on error
-	cInputToken, pinner := bytesToCBuffer(inputToken, nil)
-	defer pinner.Unpin()
-
-	mech := g.Oid{} // empty oid mapped to GSS_C_NO_OID by oid2Coid
-	if c.mech != nil {
-		mech = c.mech.Oid()
-	}
-	cMechOid, _ := oid2Coid(mech, pinner)
-
 	if c.isInitiator {
-		cMajor = C.gss_init_sec_context(&cMinor, C.GSS_C_NO_CREDENTIAL, &c.id, c.acceptorName.name, cMechOid, 0, 0, nil, &cInputToken, &cActualMech, &cOutToken, &cRetFlags, &cTimeRec)
-	} else {
-		// Ask for the initiator name and delegated credential if we don't already have them
-		var cpInitiatorName *C.gss_name_t = nil
-		if c.initiatorName == nil {
-			cpInitiatorName = &cInitiatorName
-		}
-		var cpGssDelegCred *C.gss_cred_id_t = nil
-		if c.delegCred == nil {
-			cpGssDelegCred = &cGssDelegCred
-		}
-
-		cMajor = C.gss_accept_sec_context(&cMinor, &c.id, C.GSS_C_NO_CREDENTIAL, &cInputToken, nil, cpInitiatorName, &cActualMech, &cOutToken, &cRetFlags, &cTimeRec, cpGssDelegCred)
+		return c.initSecContext(inputToken)
 	}
 
-	// *1  release GSSAPI allocated buffer
-	defer C.gss_release_buffer(&cMinor, &cOutToken)
+	return c.acceptSecContext(inputToken)
+}
 
-	var outToken []byte = nil
-	if cOutToken.length > 0 {
-		outToken = C.GoBytes(cOutToken.value, C.int(cOutToken.length))
-	}
+var errAbandoned = fmt.Errorf("%w: context establishment was abandoned by a cancelled call", g.ErrNoContext)
 
-	if cMajor != C.GSS_S_COMPLETE && (cMajor&C.GSS_S_CONTINUE_NEEDED) == 0 {
-		var errs []error
-		errs = append(errs, gssRelease(gssReleaseCred, &cGssDelegCred))  // *3   release delegated credential
-		errs = append(errs, gssRelease(gssReleaseName, &cInitiatorName)) // *2   release initiator name
-		errs = append(errs, makeStatus(cMajor, cMinor))
-		return outToken, g.SecContextInfoPartial{}, errors.Join(errs...)
+// ContinueContext is like Continue but returns ctx.Err() if ctx is cancelled or its deadline
+// passes before the underlying GSSAPI call returns.  That call may be waiting on the KDC and
+// cannot be interrupted, so it is left to finish in the background and the partially established
+// context is deleted when it does.  The context cannot be used again after a cancellation.
+//
+// Any credential supplied in the InitSecContext or AcceptSecContext options must remain valid
+// until the abandoned call has completed.
+func (c *SecContext) ContinueContext(ctx context.Context, inputToken []byte) ([]byte, g.SecContextInfoPartial, error) {
+	if c.abandoned {
+		return nil, g.SecContextInfoPartial{}, errAbandoned
 	}
 
-	// only if we're an acceptor and didn't already have an initiator name
-	if cInitiatorName != C.GSS_C_NO_NAME {
-		c.initiatorName = nameFromGssInternal(cInitiatorName)
+	type result struct {
+		outToken []byte
+		info     g.SecContextInfoPartial
+		err      error
 	}
 
-	c.continueNeeded = cMajor&C.GSS_S_CONTINUE_NEEDED > 0
+	// Work on a copy so that an abandoned call never touches the caller's object.  The
+	// caller is free to reuse the input token once we return.
+	work := *c
+	token := bytes.Clone(inputToken)
 
-	ctxFlags, protFlag, transFlag := splitFlags(cRetFlags)
+	ret, started, err := runCancelable(ctx, func() result {
+		outToken, info, err := work.Continue(token)
+		return result{outToken, info, err}
+	}, func(result) {
+		_, _ = work.Delete()
+	})
 
-	if cGssDelegCred != C.GSS_C_NO_CREDENTIAL {
-		c.delegCred = &Credential{id: cGssDelegCred, usage: g.CredUsageInitiateOnly}
+	// nothing was called, so c is still intact and usable
+	if !started {
+		return nil, g.SecContextInfoPartial{}, err
 	}
 
-	info := g.SecContextInfoPartial{
-		InitiatorName:       c.initiatorName,
-		Flags:               ctxFlags,
-		ExpiresAt:           timeRecToGssLifetime(cTimeRec),
-		LocallyInitiated:    c.isInitiator,
-		FullyEstablished:    !c.continueNeeded,
-		ProtectionReady:     protFlag,
-		Transferrable:       transFlag,
-		DelegatedCredential: c.delegCred,
-	}
-	if cActualMech != C.GSS_C_NO_OID {
-		mech, err := g.MechFromOid(oidFromGssOid(cActualMech))
-		if err != nil {
-			fnName := "gss_accept_sec_context"
-			if c.isInitiator {
-				fnName = "gss_init_sec_context"
-			}
-			return outToken, info, fmt.Errorf("unknown mech returned from %s: %w", fnName, g.ErrBadMech)
-		}
-		info.Mech = mech
+	if err != nil {
+		// work now owns the GSSAPI context and names shared with c
+		c.id = C.GSS_C_NO_CONTEXT
+		c.initiatorName = nil
+		c.acceptorName = nil
+		c.delegCred = nil
+		c.continueNeeded = false
+		c.abandoned = true
+		return nil, g.SecContextInfoPartial{}, err
 	}
 
-	return outToken, info, nil
+	*c = work
+	return ret.outToken, ret.info, ret.err
 }
 
 func (c *SecContext) ContinueNeeded() bool {
@@ -484,7 +499,7 @@ func (c *SecContext) Inquire() (*g.SecContextInfo, error) {
 	c.acceptorName = nameFromGssInternal(cTargName)
 
 	oid := oidFromGssOid(cMechOid)
-	mech, err := g.MechFromOid(oid)
+	mech, err := mechFromOid(oid)
 	if err != nil {
 		if cMechOid == C.GSS_C_NO_OID && isMacGssapi() {
 			mech = g.GSS_MECH_KRB5
@@ -0,0 +1,276 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+/*
+#include "gss.h"
+
+// The function pointer is set to the library function by the init function using symbolMap.Apply()
+OM_uint32 (*__gogssapi_inquire_sec_context_by_oid)(OM_uint32 *minor_status,
+	const gss_ctx_id_t context_handle,
+	const gss_OID desired_object,
+	gss_buffer_set_t *data_set) = NULL;
+
+OM_uint32 _gogssapi_inquire_sec_context_by_oid(OM_uint32 *minor_status, const gss_ctx_id_t context_handle, const gss_OID desired_object, gss_buffer_set_t *data_set) {
+	if( __gogssapi_inquire_sec_context_by_oid == NULL ) {
+		*minor_status = 0;
+		return GSS_S_UNAVAILABLE;
+	}
+	return _GOGSSAPI_LOCKED(__gogssapi_inquire_sec_context_by_oid(minor_status, context_handle, desired_object, data_set));
+}
+
+OM_uint32 (*__gogssapi_set_sec_context_option)(OM_uint32 *minor_status,
+	gss_ctx_id_t *context_handle,
+	const gss_OID desired_object,
+	const gss_buffer_t value) = NULL;
+
+OM_uint32 _gogssapi_set_sec_context_option(OM_uint32 *minor_status, gss_ctx_id_t *context_handle, const gss_OID desired_object, const gss_buffer_t value) {
+	if( __gogssapi_set_sec_context_option == NULL ) {
+		*minor_status = 0;
+		return GSS_S_UNAVAILABLE;
+	}
+	return _GOGSSAPI_LOCKED(__gogssapi_set_sec_context_option(minor_status, context_handle, desired_object, value));
+}
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// Map optional symbols from the GSSAPI library to the wrapper function pointers
+var secContextSymbols = symbolMap{
+	"gss_inquire_sec_context_by_oid": &C.__gogssapi_inquire_sec_context_by_oid,
+	"gss_set_sec_context_option":     &C.__gogssapi_set_sec_context_option,
+}
+
+func init() {
+	secContextSymbols.Apply()
+}
+
+// OIDs that can be passed to SecContext.InquireByOid
+var (
+	// The key protecting the context, as returned by SSPI for SECPKG_ATTR_SESSION_KEY.  MIT and
+	// recent Heimdal also return the encryption type as a second buffer.
+	GSS_C_INQ_SSPI_SESSION_KEY = mustString2Oid("1.2.840.113554.1.2.2.5.5")
+	// The key used to protect NegoEx messages, followed by its encryption type (MIT 1.18)
+	GSS_C_INQ_NEGOEX_KEY = mustString2Oid("1.2.840.113554.1.2.2.5.16")
+	// The key used to verify NegoEx messages from the peer, followed by its encryption type (MIT 1.18)
+	GSS_C_INQ_NEGOEX_VERIFY_KEY = mustString2Oid("1.2.840.113554.1.2.2.5.17")
+	// MIT prefix for Kerberos authorization data; the authorization data type is added as the final arc
+	GSS_KRB5_EXTRACT_AUTHZ_DATA_FROM_SEC_CONTEXT_OID = mustString2Oid("1.2.840.113554.1.2.2.5.10")
+	// Heimdal prefix for Kerberos authorization data; the authorization data type is added as the final arc
+	GSS_KRB5_EXTRACT_AUTHZ_DATA_FROM_SEC_CONTEXT_X = mustString2Oid("1.2.752.43.13.3")
+	// The Heimdal token key in krb5_store_keyblock format
+	GSS_KRB5_GET_SUBKEY_X = mustString2Oid("1.2.752.43.13.8")
+)
+
+// The MIT session key inquiries name the encryption type with this OID and the enctype as the final arc
+const krb5SessionKeyEnctypeOid = "1.2.840.113554.1.2.2.4"
+
+// Kerberos authorization data types (RFC 4120 § 7.5.4)
+const (
+	AuthzDataIfRelevant = 1
+	AuthzDataWin2kPAC   = 128
+)
+
+// ContextKey is a key returned by the session key inquiries.  It is secret and should be
+// cleared by the caller when it is no longer needed.
+type ContextKey struct {
+	Enctype int32 // Kerberos encryption type, or zero if the library does not report it
+	Key     []byte
+}
+
+// InquireByOid implements part of the SecContextExtGGF extension, returning the data set
+// the library associates with oid for the context (GFD.24 § 2.3.1).  GSS_S_UNAVAILABLE is
+// returned if the library or mechanism does not support the OID.
+func (c *SecContext) InquireByOid(oid g.Oid) ([][]byte, error) {
+	cOid, pinner := oid2Coid(oid, nil)
+	defer pinner.Unpin()
+
+	var cMinor C.OM_uint32
+	var cDataSet C.gss_buffer_set_t = C.GSS_C_NO_BUFFER_SET // allocated by GSSAPI; released by *1
+	cMajor := C._gogssapi_inquire_sec_context_by_oid(&cMinor, c.id, cOid, &cDataSet)
+	if cMajor != C.GSS_S_COMPLETE {
+		return nil, makeMechStatus(cMajor, cMinor, c.mech)
+	}
+
+	// *1 Free buffers
+	defer C.gss_release_buffer_set(&cMinor, &cDataSet)
+
+	if cDataSet == C.GSS_C_NO_BUFFER_SET {
+		return [][]byte{}, nil
+	}
+
+	return extractBufferSet(cDataSet), nil
+}
+
+// Options for SecContext.SetOption
+var (
+	// Heimdal: make MICs for DES3 contexts in the form used by old versions of the library.  The
+	// value is one byte, non-zero to enable the option.
+	GSS_KRB5_COMPAT_DES3_MIC_X = mustString2Oid("1.2.752.43.13.4")
+)
+
+// SetOption implements part of the SecContextExtGGF extension, setting an option of the context
+// with gss_set_sec_context_option (GFD.24 § 2.4.1).  The options are identified by OID and are
+// specific to the implementation.  The context must have been created by the first call to
+// Continue; options that affect the start of context establishment are set on the credential
+// instead (see Credential.SetOption).  GSS_S_UNAVAILABLE is returned if the library does not
+// support the function and usually if it does not understand the option.
+//
+// The DCE style exchange is not an option but is requested with g.ContextFlagDceStyle.
+func (c *SecContext) SetOption(option g.Oid, value []byte) error {
+	if c.id == nil {
+		return g.ErrNoContext
+	}
+	if len(value) > math.MaxUint32 {
+		return ErrTooLarge
+	}
+
+	pinner := &runtime.Pinner{}
+	defer pinner.Unpin()
+	cOption, _ := oid2Coid(option, pinner)
+	cValue, _ := bytesToCBuffer(value, pinner)
+
+	var cMinor C.OM_uint32
+	cMajor := C._gogssapi_set_sec_context_option(&cMinor, &c.id, cOption, &cValue)
+	return makeMechStatus(cMajor, cMinor, c.mech)
+}
+
+// SessionKey returns the key protecting the messages of an established context, which is the
+// acceptor subkey, initiator subkey or ticket session key for Kerberos.  This is the key that
+// SMB and LDAP signing expect.
+func (c *SecContext) SessionKey() (ContextKey, error) {
+	return c.inquireKey(GSS_C_INQ_SSPI_SESSION_KEY)
+}
+
+// SessionKeyEnctype returns the Kerberos encryption type of the session key
+func (c *SecContext) SessionKeyEnctype() (int32, error) {
+	key, err := c.SessionKey()
+	if err != nil {
+		return 0, err
+	}
+	clear(key.Key)
+
+	if key.Enctype == 0 {
+		return 0, makeCustomStatus(C.GSS_S_UNAVAILABLE, errors.New("the library did not report the session key encryption type"))
+	}
+
+	return key.Enctype, nil
+}
+
+// NegoexKey returns the key that protects the NegoEx messages sent by this side of the context
+func (c *SecContext) NegoexKey() (ContextKey, error) {
+	return c.inquireKey(GSS_C_INQ_NEGOEX_KEY)
+}
+
+// NegoexVerifyKey returns the key that verifies the NegoEx messages sent by the peer
+func (c *SecContext) NegoexVerifyKey() (ContextKey, error) {
+	return c.inquireKey(GSS_C_INQ_NEGOEX_VERIFY_KEY)
+}
+
+// AuthzData returns the elements of the Kerberos authorization data of type adType from the
+// ticket used to establish an acceptor context, for example AuthzDataWin2kPAC.
+func (c *SecContext) AuthzData(adType int32) ([][]byte, error) {
+	if adType < 0 {
+		return nil, fmt.Errorf("bad authorization data type %d: %w", adType, g.ErrFailure)
+	}
+
+	base := GSS_KRB5_EXTRACT_AUTHZ_DATA_FROM_SEC_CONTEXT_OID
+	if isHeimdal() {
+		base = GSS_KRB5_EXTRACT_AUTHZ_DATA_FROM_SEC_CONTEXT_X
+	}
+
+	baseStr, err := oid2String(base)
+	if err != nil {
+		return nil, err
+	}
+	oid, err := string2Oid(baseStr + "." + strconv.Itoa(int(adType)))
+	if err != nil {
+		return nil, err
+	}
+
+	return c.InquireByOid(oid)
+}
+
+func (c *SecContext) inquireKey(oid g.Oid) (ContextKey, error) {
+	data, err := c.InquireByOid(oid)
+	if err != nil {
+		return ContextKey{}, err
+	}
+	if len(data) == 0 || len(data[0]) == 0 {
+		return ContextKey{}, makeCustomStatus(C.GSS_S_UNAVAILABLE, errors.New("the library did not return a key"))
+	}
+
+	key := ContextKey{Key: data[0]}
+	switch {
+	case len(data) > 1:
+		key.Enctype, err = enctypeFromOid(data[1])
+		if err != nil {
+			clear(key.Key)
+			return ContextKey{}, err
+		}
+	case isHeimdal():
+		// older Heimdal releases return only the key, but the same key is available with its
+		// encryption type as the token key
+		sub, err := c.InquireByOid(GSS_KRB5_GET_SUBKEY_X)
+		if err == nil && len(sub) > 0 {
+			kb, err := parseHeimdalKeyblock(sub[0])
+			if err == nil && bytes.Equal(kb.Key, key.Key) {
+				key.Enctype = kb.Enctype
+			}
+			clear(sub[0])
+		}
+	}
+
+	return key, nil
+}
+
+// enctypeFromOid decodes the encryption type OID returned by the MIT session key inquiries
+func enctypeFromOid(oid g.Oid) (int32, error) {
+	s, err := oid2String(oid)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad encryption type OID: %w", ErrMalformedToken, err)
+	}
+
+	arc, ok := strings.CutPrefix(s, krb5SessionKeyEnctypeOid+".")
+	if !ok || strings.Contains(arc, ".") {
+		return 0, fmt.Errorf("%w: unexpected encryption type OID %s", ErrMalformedToken, s)
+	}
+
+	etype, err := strconv.ParseInt(arc, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad encryption type OID %s", ErrMalformedToken, s)
+	}
+
+	return int32(etype), nil
+}
+
+// parseHeimdalKeyblock decodes a key written by Heimdal's krb5_store_keyblock: a 16 bit
+// encryption type and the key as 32 bit length-prefixed data, all big endian.
+func parseHeimdalKeyblock(b []byte) (ContextKey, error) {
+	if len(b) < 6 {
+		return ContextKey{}, fmt.Errorf("%w: truncated keyblock", ErrMalformedToken)
+	}
+
+	etype := int16(binary.BigEndian.Uint16(b))
+	length := binary.BigEndian.Uint32(b[2:])
+	if uint64(length) != uint64(len(b)-6) {
+		return ContextKey{}, fmt.Errorf("%w: keyblock length %d does not match the data (%d bytes)", ErrMalformedToken, length, len(b)-6)
+	}
+
+	return ContextKey{
+		Enctype: int32(etype),
+		Key:     bytes.Clone(b[6:]),
+	}, nil
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"testing"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+func TestEnctypeFromOid(t *testing.T) {
+	assert := NewAssert(t)
+
+	oid, err := string2Oid(krb5SessionKeyEnctypeOid + ".18")
+	assert.NoErrorFatal(err)
+	etype, err := enctypeFromOid(oid)
+	assert.NoError(err)
+	assert.Equal(int32(EnctypeAes256CtsHmacSha196), etype)
+
+	for _, bad := range []string{"1.2.840.113554.1.2.2.5.18", krb5SessionKeyEnctypeOid, krb5SessionKeyEnctypeOid + ".18.1"} {
+		oid, err := string2Oid(bad)
+		assert.NoErrorFatal(err)
+		_, err = enctypeFromOid(oid)
+		assert.ErrorIs(err, ErrMalformedToken, bad)
+	}
+}
+
+func TestParseHeimdalKeyblock(t *testing.T) {
+	assert := NewAssert(t)
+
+	kb, err := parseHeimdalKeyblock([]byte{0, 17, 0, 0, 0, 4, 1, 2, 3, 4})
+	assert.NoError(err)
+	assert.Equal(ContextKey{Enctype: EnctypeAes128CtsHmacSha196, Key: []byte{1, 2, 3, 4}}, kb)
+
+	_, err = parseHeimdalKeyblock([]byte{0, 17, 0, 0})
+	assert.ErrorIs(err, ErrMalformedToken)
+	_, err = parseHeimdalKeyblock([]byte{0, 17, 0, 0, 0, 5, 1, 2, 3, 4})
+	assert.ErrorIs(err, ErrMalformedToken)
+}
+
+func TestSessionKey(t *testing.T) {
+	assert := NewAssert(t)
+	if !hasSymbol("gss_inquire_sec_context_by_oid") {
+		t.Skip("gss_inquire_sec_context_by_oid is not available in this GSSAPI library")
+	}
+
+	initiator, acceptor := establishContexts(t, g.ContextFlagMutual)
+
+	initKey, err := initiator.SessionKey()
+	assert.NoErrorFatal(err)
+	assert.NotEmpty(initKey.Key)
+
+	accKey, err := acceptor.SessionKey()
+	assert.NoErrorFatal(err)
+	assert.Equal(initKey, accKey)
+
+	if initKey.Enctype != 0 {
+		etype, err := acceptor.SessionKeyEnctype()
+		assert.NoError(err)
+		assert.Equal(initKey.Enctype, etype)
+
+		switch etype {
+		case EnctypeAes128CtsHmacSha196, EnctypeAes128CtsHmacSha256128:
+			assert.Len(initKey.Key, 16)
+		case EnctypeAes256CtsHmacSha196, EnctypeAes256CtsHmacSha384192:
+			assert.Len(initKey.Key, 32)
+		}
+	}
+
+	_, err = acceptor.AuthzData(-1)
+	assert.ErrorIs(err, g.ErrFailure)
+}
+
+func TestSecContextSetOption(t *testing.T) {
+	assert := NewAssert(t)
+
+	c := &SecContext{}
+	assert.ErrorIs(c.SetOption(GSS_KRB5_COMPAT_DES3_MIC_X, []byte{1}), g.ErrNoContext)
+
+	if !hasSymbol("gss_set_sec_context_option") {
+		t.Skip("gss_set_sec_context_option is not available in this GSSAPI library")
+	}
+
+	initiator, _ := establishContexts(t, g.ContextFlagMutual)
+	assert.Error(initiator.SetOption(mustString2Oid("1.3.6.1.4.1.57264.999.1"), nil))
+}
@@ -3,10 +3,16 @@
 package gssapi
 
 import (
+	"context"
 	"errors"
 	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/golang-auth/go-gssapi-c/gsstest"
 	g "github.com/golang-auth/go-gssapi/v3"
 )
 
@@ -419,6 +425,92 @@ func TestSecContextEstablishment(t *testing.T) {
 	assert.NoErrorFatal(err)
 }
 
+func TestSecContextActualMech(t *testing.T) {
+	assert := NewAssert(t)
+
+	// neither side names a mechanism, so both learn it from the library
+	initiator, acceptor := establishContexts(t, g.ContextFlagMutual)
+	assert.Equal(g.GSS_MECH_KRB5, initiator.mech)
+	assert.Equal(g.GSS_MECH_KRB5, acceptor.mech)
+}
+
+func TestContinueContext(t *testing.T) {
+	assert := NewAssert(t)
+	ta.useAsset(t, testCredCache|testKeytabRack)
+
+	name, err := ta.lib.ImportName("rack@foo.golang-auth.io", g.GSS_NT_HOSTBASED_SERVICE)
+	assert.NoErrorFatal(err)
+	defer name.Release() //nolint:errcheck
+
+	secCtxInitiator, err := ta.lib.InitSecContext(name, g.WithInitiatorFlags(g.ContextFlagMutual))
+	assert.NoErrorFatal(err)
+	defer secCtxInitiator.Delete() //nolint:errcheck
+
+	secCtxAcceptor, err := ta.lib.AcceptSecContext()
+	assert.NoErrorFatal(err)
+	defer secCtxAcceptor.Delete() //nolint:errcheck
+
+	initiator := secCtxInitiator.(*SecContext)
+	acceptor := secCtxAcceptor.(*SecContext)
+
+	ctx := context.Background()
+	var initiatorTok, acceptorTok []byte
+	for initiator.ContinueNeeded() || acceptor.ContinueNeeded() {
+		acceptorTok, _, err = initiator.ContinueContext(ctx, initiatorTok)
+		if err != nil {
+			break
+		}
+
+		if len(acceptorTok) > 0 {
+			initiatorTok, _, err = acceptor.ContinueContext(ctx, acceptorTok)
+			if err != nil {
+				break
+			}
+		}
+	}
+	assert.NoErrorFatal(err)
+
+	info, err := acceptor.Inquire()
+	assert.NoErrorFatal(err)
+	assert.True(info.FullyEstablished)
+
+	msg := []byte("Hello GSSAPI")
+	wrapped, _, err := initiator.Wrap(msg, true, 0)
+	assert.NoErrorFatal(err)
+	unwrapped, _, _, err := acceptor.Unwrap(wrapped)
+	assert.NoErrorFatal(err)
+	assert.Equal(msg, unwrapped)
+}
+
+func TestContinueContextCancelled(t *testing.T) {
+	assert := NewAssert(t)
+	ta.useAsset(t, testCredCache|testKeytabRack)
+
+	name, err := ta.lib.ImportName("rack@foo.golang-auth.io", g.GSS_NT_HOSTBASED_SERVICE)
+	assert.NoErrorFatal(err)
+	defer name.Release() //nolint:errcheck
+
+	secCtx, err := ta.lib.InitSecContext(name)
+	assert.NoErrorFatal(err)
+	defer secCtx.Delete() //nolint:errcheck
+
+	c := secCtx.(*SecContext)
+
+	// nothing is started if the context is already done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = c.ContinueContext(ctx, nil)
+	assert.ErrorIs(err, context.Canceled)
+	assert.False(c.abandoned)
+
+	// an abandoned context cannot be used again
+	c.abandoned = true
+	_, _, err = c.Continue(nil)
+	assert.ErrorIs(err, g.ErrNoContext)
+	_, _, err = c.ContinueContext(context.Background(), nil)
+	assert.ErrorIs(err, g.ErrNoContext)
+}
+
 func TestChannelBindings(t *testing.T) {
 
 	hasChBound := hasChannelBound()
@@ -505,3 +597,144 @@ func TestChannelBindings(t *testing.T) {
 		})
 	}
 }
+
+func TestAnonymousInitiator(t *testing.T) {
+	assert := NewAssert(t)
+	if isHeimdal() {
+		// the Heimdal mechanism reads krb5.conf once, so it can't be pointed at the test KDC
+		t.Skip("anonymous PKINIT is only tested with MIT Kerberos")
+	}
+
+	kdc := gsstest.StartT(t, gsstest.WithAnonymous())
+	for _, kv := range kdc.Env() {
+		k, v, _ := strings.Cut(kv, "=")
+		t.Setenv(k, v)
+	}
+
+	assert.NoErrorFatal(kdc.AddRandomKeyPrincipal("host/anon.golang-auth.io"))
+	kt, err := kdc.Keytab("host/anon.golang-auth.io")
+	assert.NoErrorFatal(err)
+	t.Setenv("KRB5_KTNAME", "FILE:"+kt)
+	t.Setenv("KRB5CCNAME", "FILE:"+filepath.Join(t.TempDir(), "ccache"))
+
+	anonName, err := ta.lib.ImportName("", g.GSS_NT_ANONYMOUS)
+	assert.NoErrorFatal(err)
+	defer releaseName(anonName)
+
+	cred, err := ta.lib.AcquireCredential(anonName, []g.GssMech{g.GSS_MECH_KRB5}, g.CredUsageInitiateOnly, nil)
+	assert.NoErrorFatal(err)
+	defer cred.Release() //nolint:errcheck
+
+	credInfo, err := cred.Inquire()
+	assert.NoErrorFatal(err)
+	assert.Equal(g.GSS_NT_ANONYMOUS, credInfo.NameType)
+
+	secCtxInitiator, secCtxAcceptor, _ := establishContextsWith(t, "host@anon.golang-auth.io", contextOptions{
+		initiator: []g.InitSecContextOption{
+			g.WithInitiatorCredential(cred),
+			g.WithInitiatorFlags(g.ContextFlagAnon | g.ContextFlagMutual | g.ContextFlagConf),
+		},
+	})
+
+	info, err := secCtxAcceptor.Inquire()
+	assert.NoErrorFatal(err)
+	assert.NotZero(info.Flags & g.ContextFlagAnon)
+
+	initiatorName, nameType, err := info.InitiatorName.Display()
+	assert.NoError(err)
+	assert.Equal(AnonymousPrincipal, initiatorName)
+	assert.Equal(g.GSS_NT_ANONYMOUS, nameType)
+
+	// the context is still protected
+	msg := []byte("Hello anonymously")
+	wrapped, hasConf, err := secCtxInitiator.Wrap(msg, true, 0)
+	assert.NoErrorFatal(err)
+	assert.True(hasConf)
+
+	unwrapped, _, _, err := secCtxAcceptor.Unwrap(wrapped)
+	assert.NoError(err)
+	assert.Equal(msg, unwrapped)
+}
+
+func TestIAKERB(t *testing.T) {
+	assert := NewAssert(t)
+	if isHeimdal() {
+		t.Skip("IAKERB is only available with MIT Kerberos")
+	}
+	mechs, err := ta.lib.IndicateMechs()
+	assert.NoErrorFatal(err)
+	if !slices.Contains(mechs, g.GssMech(g.GSS_MECH_IAKERB)) {
+		t.Skip("IAKERB is not supported by this GSSAPI library")
+	}
+
+	kdc := gsstest.StartT(t)
+	assert.NoErrorFatal(kdc.AddRandomKeyPrincipal("robot"))
+	assert.NoErrorFatal(kdc.AddRandomKeyPrincipal("host/iakerb.golang-auth.io"))
+	clientKeytab, err := kdc.Keytab("robot")
+	assert.NoErrorFatal(err)
+	keytab, err := kdc.Keytab("host/iakerb.golang-auth.io")
+	assert.NoErrorFatal(err)
+
+	// The initiator is configured with a KDC that does not exist, so all of its KDC exchanges
+	// have to be proxied by the acceptor.  Each side's krb5.conf is selected before calling
+	// into the library for that side.
+	dir := t.TempDir()
+	initiatorConf := filepath.Join(dir, "krb5.conf")
+	conf := "[libdefaults]\n\tdefault_realm = " + kdc.Realm() + "\n\tdns_lookup_kdc = false\n\trdns = false\n" +
+		"[realms]\n\t" + kdc.Realm() + " = {\n\t\tkdc = 127.0.0.1:1\n\t}\n"
+	assert.NoErrorFatal(os.WriteFile(initiatorConf, []byte(conf), 0600))
+	asInitiator := func() { t.Setenv("KRB5_CONFIG", initiatorConf) }
+	asAcceptor := func() { t.Setenv("KRB5_CONFIG", kdc.Krb5Conf()) }
+
+	t.Setenv("KRB5CCNAME", "FILE:"+filepath.Join(dir, "ccache"))
+	t.Setenv("KRB5_CLIENT_KTNAME", "FILE:"+clientKeytab)
+	t.Setenv("KRB5_KTNAME", "FILE:"+keytab)
+
+	asAcceptor()
+	acceptorCred, err := ta.lib.AcquireCredential(nil, []g.GssMech{g.GSS_MECH_IAKERB}, g.CredUsageAcceptOnly, nil)
+	assert.NoErrorFatal(err)
+	defer acceptorCred.Release() //nolint:errcheck
+
+	asInitiator()
+	clientName, err := ta.lib.ImportName(kdc.Principal("robot"), g.GSS_KRB5_NT_PRINCIPAL_NAME)
+	assert.NoErrorFatal(err)
+	defer releaseName(clientName)
+
+	initiatorCred, err := ta.lib.AcquireCredential(clientName, []g.GssMech{g.GSS_MECH_IAKERB}, g.CredUsageInitiateOnly, nil)
+	assert.NoErrorFatal(err)
+	defer initiatorCred.Release() //nolint:errcheck
+
+	secCtxInitiator, secCtxAcceptor, rounds := establishContextsWith(t, "host@iakerb.golang-auth.io", contextOptions{
+		initiator: []g.InitSecContextOption{
+			g.WithInitiatorMech(g.GSS_MECH_IAKERB),
+			g.WithInitiatorCredential(initiatorCred),
+			g.WithInitiatorFlags(g.ContextFlagMutual | g.ContextFlagConf | g.ContextFlagInteg),
+		},
+		acceptor:    []g.AcceptSecContextOption{g.WithAcceptorCredential(acceptorCred)},
+		asInitiator: asInitiator,
+		asAcceptor:  asAcceptor,
+	})
+
+	// an AS and a TGS exchange proxied by the acceptor, then the AP exchange
+	assert.GreaterOrEqual(rounds, 3)
+	// the flags requested on the first call still apply to the AP-REQ sent in the last one
+	initiatorInfo, err := secCtxInitiator.Inquire()
+	assert.NoErrorFatal(err)
+	assert.NotZero(initiatorInfo.Flags & g.ContextFlagMutual)
+	assert.NotZero(initiatorInfo.Flags & g.ContextFlagConf)
+
+	info, err := secCtxAcceptor.Inquire()
+	assert.NoErrorFatal(err)
+	initiatorName, _, err := info.InitiatorName.Display()
+	assert.NoError(err)
+	assert.Equal(kdc.Principal("robot"), initiatorName)
+
+	msg := []byte("Hello via the acceptor")
+	wrapped, hasConf, err := secCtxInitiator.Wrap(msg, true, 0)
+	assert.NoErrorFatal(err)
+	assert.True(hasConf)
+
+	unwrapped, _, _, err := secCtxAcceptor.Unwrap(wrapped)
+	assert.NoError(err)
+	assert.Equal(msg, unwrapped)
+}
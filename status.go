@@ -94,30 +94,145 @@ func (s FatalCallingError) Error() string {
 	return ret
 }
 
+// MechError is a mechanism specific error reported by the GSSAPI library using the minor status
+// code.  The C libraries describe the code with a text message which varies between implementations,
+// so callers should not match on Message.  Instead, well known Kerberos errors can be detected using
+// [IsKrb5Error] with the ErrKrb5* values, which work the same way for MIT and Heimdal.  The MechError
+// values are found in the MechErrors field of the status returned by the provider.
+type MechError struct {
+	// MinorCode is the minor status code returned by the GSSAPI library.  Kerberos codes are
+	// com_err values from the krb5 error table that both MIT and Heimdal use.
+	MinorCode uint32
+	// Message is the description of the code returned by gss_display_status.
+	Message string
+}
+
+// Error implements error.Error()
+func (e MechError) Error() string {
+	return e.Message
+}
+
+// Unwrap implements errors.Unwrap(), returning the ErrKrb5* value corresponding to
+// the minor code, or nil if there is none.
+func (e MechError) Unwrap() error {
+	return krb5Errors[e.MinorCode]
+}
+
+// Well known Kerberos errors that may be reported as the minor status of a GSSAPI call
+var (
+	// ErrKrb5ClockSkew indicates that the clocks of the peers (or a peer and the KDC) are too far apart
+	ErrKrb5ClockSkew = errors.New("kerberos: clock skew too great")
+	// ErrKrb5PrincipalUnknown indicates that the client or server principal is not known to the KDC
+	ErrKrb5PrincipalUnknown = errors.New("kerberos: principal unknown")
+	// ErrKrb5PreauthFailed indicates that pre-authentication failed, usually because of a bad password
+	ErrKrb5PreauthFailed = errors.New("kerberos: pre-authentication failed")
+	// ErrKrb5WrongKeyVersion indicates that the ticket was encrypted with a key version that is not
+	// available to the acceptor
+	ErrKrb5WrongKeyVersion = errors.New("kerberos: wrong key version")
+	// ErrKrb5TicketExpired indicates that the ticket has expired
+	ErrKrb5TicketExpired = errors.New("kerberos: ticket expired")
+	// ErrKrb5NoCredentialsCache indicates that the credentials cache does not exist
+	ErrKrb5NoCredentialsCache = errors.New("kerberos: no credentials cache found")
+	// ErrKrb5Replay indicates that the acceptor detected a replayed authenticator
+	ErrKrb5Replay = errors.New("kerberos: request is a replay")
+)
+
+// Codes from the krb5 com_err table, which has the same base in MIT and Heimdal
+const (
+	krb5ErrorTableBase = 0x96c73a00 // ERROR_TABLE_BASE_krb5 (-1765328384)
+
+	krb5KdcErrCPrincipalUnknown = krb5ErrorTableBase + 6
+	krb5KdcErrSPrincipalUnknown = krb5ErrorTableBase + 7
+	krb5KdcErrPreauthFailed     = krb5ErrorTableBase + 24
+	krb5ApErrTktExpired         = krb5ErrorTableBase + 32
+	krb5ApErrRepeat             = krb5ErrorTableBase + 34
+	krb5ApErrSkew               = krb5ErrorTableBase + 37
+	krb5ApErrBadKeyVer          = krb5ErrorTableBase + 44
+	krb5FccNoFile               = krb5ErrorTableBase + 195
+	krb5KtKvnoNotFound          = krb5ErrorTableBase + 210
+)
+
+var krb5Errors = map[uint32]error{
+	krb5KdcErrCPrincipalUnknown: ErrKrb5PrincipalUnknown,
+	krb5KdcErrSPrincipalUnknown: ErrKrb5PrincipalUnknown,
+	krb5KdcErrPreauthFailed:     ErrKrb5PreauthFailed,
+	krb5ApErrTktExpired:         ErrKrb5TicketExpired,
+	krb5ApErrRepeat:             ErrKrb5Replay,
+	krb5ApErrSkew:               ErrKrb5ClockSkew,
+	krb5ApErrBadKeyVer:          ErrKrb5WrongKeyVersion,
+	krb5FccNoFile:               ErrKrb5NoCredentialsCache,
+	krb5KtKvnoNotFound:          ErrKrb5WrongKeyVersion,
+}
+
+// IsKrb5Error reports whether err describes the Kerberos error target, which is one of the
+// ErrKrb5* values.  The go-gssapi status types keep the [MechError] values for the minor
+// status in their MechErrors field rather than unwrapping to them, so [errors.Is] can not see
+// them; IsKrb5Error looks there too.
+func IsKrb5Error(err, target error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, target) {
+		return true
+	}
+
+	var mechErrors []error
+	switch e := err.(type) {
+	case FatalCallingError:
+		mechErrors = e.MechErrors
+	case g.FatalStatus:
+		mechErrors = e.MechErrors
+	case g.InfoStatus:
+		mechErrors = e.MechErrors
+	case interface{ Unwrap() error }:
+		mechErrors = []error{e.Unwrap()}
+	case interface{ Unwrap() []error }:
+		mechErrors = e.Unwrap()
+	}
+
+	for _, mechErr := range mechErrors {
+		if IsKrb5Error(mechErr, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func makeStatus(major, minor C.OM_uint32) error {
 	return makeMechStatus(major, minor, nil)
 }
 
+// makeCustomStatus returns the go-gssapi status type for major, describing it with customErrs
+// in place of mechanism errors
 func makeCustomStatus(major C.OM_uint32, customErrs ...error) error {
-	err := makeMechStatus(major, 0, nil)
-
-	switch err := err.(type) {
-	case g.InfoStatus:
-		err.MechErrors = customErrs
-		return err
-	case g.FatalStatus:
-		err.MechErrors = customErrs
-		return err
+	if major == C.GSS_S_COMPLETE {
+		return nil
 	}
 
-	return err
+	return majorStatus(major, customErrs)
 }
 
+// makeMechStatus returns the status for major, with the minor status described by MechErrors
+// looked up for mech
 func makeMechStatus(major, minor C.OM_uint32, mech g.GssMech) error {
 	if major == C.GSS_S_COMPLETE {
 		return nil
 	}
 
+	// minor codes are specific to the mech; there are no standard codes
+	// so we deposit error objects with the code and the description strings
+	// from the C API
+	var mechErrors []error
+	if minor != 0 {
+		mechErrors = gssMinorErrors(minor, mech)
+	}
+
+	return majorStatus(major, mechErrors)
+}
+
+// majorStatus returns the InfoStatus, FatalStatus or FatalCallingError for major
+func majorStatus(major C.OM_uint32, mechErrors []error) error {
 	// see RFC 2744 § 3.9.1
 	callingError := (major & 0xFF000000) >> 24 // bad call by us to gssapi
 	routineError := (major & 0x00FF0000) >> 16 // the "Fatal" errors
@@ -127,15 +242,8 @@ func makeMechStatus(major, minor C.OM_uint32, mech g.GssMech) error {
 	info := g.InfoStatus{
 		InformationCode: g.InformationCode(supplementary),
 	}
-
-	// minor codes are specific to the mech; there are no standard codes
-	// so we just deposit error objects with description strings from
-	// the C API
-	if minor != 0 {
-		minorErrors := gssMinorErrors(minor, mech)
-		if len(minorErrors) > 0 {
-			info.MechErrors = minorErrors
-		}
+	if len(mechErrors) > 0 {
+		info.MechErrors = mechErrors
 	}
 
 	// its just an informational if there is no calling or routine error
@@ -181,12 +289,18 @@ func gssMinorErrors(mechStatus C.OM_uint32, mech g.GssMech) []error {
 		major := C.gss_display_status(&minor, mechStatus, 2, cMechOid, &msgCtx, &statusString)
 		if major != C.GSS_S_COMPLETE {
 			// specifically do not call makeStatus here - we might end up in a loop..
-			ret = append(ret, fmt.Errorf("got GSS error %d/%d while finding string for minor code %d", major, minor, mechStatus))
+			ret = append(ret, MechError{
+				MinorCode: uint32(mechStatus),
+				Message:   fmt.Sprintf("got GSS error %d/%d while finding string for minor code %d", major, minor, mechStatus),
+			})
 			break
 		}
 
 		s := C.GoStringN((*C.char)(statusString.value), C.int(statusString.length))
-		ret = append(ret, errors.New(s))
+		ret = append(ret, MechError{
+			MinorCode: uint32(mechStatus),
+			Message:   s,
+		})
 
 		// *1 Release buffer
 		C.gss_release_buffer(&minor, &statusString)
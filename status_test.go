@@ -3,6 +3,8 @@
 package gssapi
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 
 	g "github.com/golang-auth/go-gssapi/v3"
@@ -73,3 +75,85 @@ func TestMakeStatus(t *testing.T) {
 	assert.ErrorIs(err, g.ErrBadNameType)
 	assert.ErrorIs(err, g.InfoContinueNeeded)
 }
+
+func TestMechError(t *testing.T) {
+	assert := NewAssert(t)
+
+	err := MechError{MinorCode: krb5ApErrSkew, Message: "Clock skew too great"}
+	assert.Equal("Clock skew too great", err.Error())
+	assert.ErrorIs(err, ErrKrb5ClockSkew)
+	assert.NotErrorIs(err, ErrKrb5Replay)
+
+	err = MechError{MinorCode: 12345, Message: "something else"}
+	assert.Nil(err.Unwrap())
+
+	assert.ErrorIs(MechError{MinorCode: krb5KdcErrSPrincipalUnknown}, ErrKrb5PrincipalUnknown)
+	assert.ErrorIs(MechError{MinorCode: krb5KdcErrCPrincipalUnknown}, ErrKrb5PrincipalUnknown)
+	assert.ErrorIs(MechError{MinorCode: krb5KdcErrPreauthFailed}, ErrKrb5PreauthFailed)
+	assert.ErrorIs(MechError{MinorCode: krb5ApErrBadKeyVer}, ErrKrb5WrongKeyVersion)
+	assert.ErrorIs(MechError{MinorCode: krb5KtKvnoNotFound}, ErrKrb5WrongKeyVersion)
+	assert.ErrorIs(MechError{MinorCode: krb5ApErrTktExpired}, ErrKrb5TicketExpired)
+	assert.ErrorIs(MechError{MinorCode: krb5FccNoFile}, ErrKrb5NoCredentialsCache)
+	assert.ErrorIs(MechError{MinorCode: krb5ApErrRepeat}, ErrKrb5Replay)
+}
+
+func TestMakeStatusMinor(t *testing.T) {
+	assert := NewAssert(t)
+
+	// GSS_S_FAILURE with a clock skew minor code
+	err := makeStatus(0x0d<<16, krb5ApErrSkew)
+	assert.ErrorIs(err, g.ErrFailure)
+	assert.True(IsKrb5Error(err, ErrKrb5ClockSkew))
+	assert.False(IsKrb5Error(err, ErrKrb5Replay))
+
+	// the status is the go-gssapi type, with the minor status in MechErrors
+	assert.IsType(g.FatalStatus{}, err)
+	fatal := err.(g.FatalStatus)
+	assert.Equal(g.FatalErrorCode(0x0d), fatal.FatalErrorCode)
+	assert.NotEmpty(fatal.MechErrors)
+	mechErr, ok := fatal.MechErrors[0].(MechError)
+	assert.True(ok)
+	assert.Equal(uint32(krb5ApErrSkew), mechErr.MinorCode)
+	assert.NotEmpty(mechErr.Message)
+
+	// informational status too
+	err = makeStatus(0x01, krb5ApErrSkew)
+	assert.IsType(g.InfoStatus{}, err)
+	assert.True(IsKrb5Error(err, ErrKrb5ClockSkew))
+
+	// calling errors
+	err = makeStatus(0x01<<24|0x0d<<16, krb5ApErrRepeat)
+	assert.IsType(FatalCallingError{}, err)
+	assert.ErrorIs(err, ErrInaccessibleRead)
+	assert.True(IsKrb5Error(err, ErrKrb5Replay))
+
+	// wrapped statuses
+	assert.True(IsKrb5Error(fmt.Errorf("accepting: %w", makeStatus(0x0d<<16, krb5ApErrSkew)), ErrKrb5ClockSkew))
+	assert.True(IsKrb5Error(errors.Join(errors.New("other"), makeStatus(0x0d<<16, krb5ApErrSkew)), ErrKrb5ClockSkew))
+
+	// no minor code
+	err = makeStatus(0x0d<<16, 0)
+	assert.IsType(g.FatalStatus{}, err)
+	assert.Empty(err.(g.FatalStatus).MechErrors)
+	assert.False(IsKrb5Error(err, ErrKrb5ClockSkew))
+
+	// errors that are not statuses
+	assert.True(IsKrb5Error(MechError{MinorCode: krb5ApErrSkew}, ErrKrb5ClockSkew))
+	assert.False(IsKrb5Error(nil, ErrKrb5ClockSkew))
+}
+
+func TestMakeCustomStatus(t *testing.T) {
+	assert := NewAssert(t)
+
+	custom := errors.New("custom error")
+	err := makeCustomStatus(0x0d<<16, custom)
+	assert.IsType(g.FatalStatus{}, err)
+	assert.ErrorIs(err, g.ErrFailure)
+	assert.Equal([]error{custom}, err.(g.FatalStatus).MechErrors)
+
+	err = makeCustomStatus(0x01, custom)
+	assert.IsType(g.InfoStatus{}, err)
+	assert.Equal([]error{custom}, err.(g.InfoStatus).MechErrors)
+
+	assert.NoError(makeCustomStatus(0))
+}
@@ -12,11 +12,29 @@ import (
 #include <dlfcn.h>
 #include <stdint.h>
 #include <stdlib.h>
+
+static void *_gogssapi_dlsym(void *handle, const char *symbol) {
+	if( handle == NULL ) {
+		handle = RTLD_DEFAULT;
+	}
+	return dlsym(handle, symbol);
+}
 */
 import "C"
 
-// Maps symbols that we look for in the binary to their addresses
-var optionalSymbols = make(map[string]unsafe.Pointer)
+// Maps symbols that we look for in the binary to their addresses.  The map is replaced, never
+// modified, when a library is opened; symbolsMutex guards the variable.
+var (
+	optionalSymbols = make(map[string]unsafe.Pointer)
+	symbolsMutex    sync.RWMutex
+)
+
+// The GSSAPI library opened by loadLibrary, or nil to search the binary and the libraries
+// it was linked against
+var libraryHandle unsafe.Pointer
+
+// Symbol maps that have been applied, so they can be applied again if a library is opened
+var appliedSymbolMaps []*symbolMap
 
 // This runs once via sync.Once
 func readSymbols() {
@@ -31,25 +49,80 @@ func readSymbols() {
 		"gss_acquire_cred_from", // Credential Store extension
 		"gss_store_cred_into",   // Credential Store extension
 		"gss_add_cred_from",     // Credential Store extension
+		"gss_store_cred",        // Used when gss_store_cred_into is missing
+		"gss_krb5_ccache_name",  // ditto
+		"krb5_init_context",     // krb5 credentials cache routines
+		"krb5_free_context",
+		"krb5_cc_new_unique",
+		"krb5_cc_resolve",
+		"krb5_cc_get_full_name",
+		"krb5_cc_close",
+		"krb5_cc_destroy",
+		"krb5_get_error_message",
+		"krb5_free_error_message",
+		"heimdal_version",   // Heimdal release, reported by Info
+		"gss_pseudo_random", // RFC 4401, not in old Apple and FreeBSD Heimdal
+		"gss_inquire_sec_context_by_oid",
+		"gss_krb5_export_lucid_sec_context",
+		"gss_krb5_free_lucid_sec_context",
+		"gsskrb5_extract_authtime_from_sec_context",
+		"gss_krb5_get_tkt_flags",
+		"gsskrb5_extract_authz_data_from_sec_context",
+		"gss_krb5_set_allowable_enctypes",
+		"gss_set_cred_option",
+		"gss_set_sec_context_option",
+		"gss_krb5_import_cred",
+		"krb5_kt_resolve",
+		"krb5_kt_close",
+		"krb5_parse_name",
+		"krb5_free_principal",
 	}
 
+	symbols := make(map[string]unsafe.Pointer, len(syms))
 	for _, sym := range syms {
-		symStr := C.CString(sym)
-		ptr := C.dlsym(C.RTLD_DEFAULT, symStr)
-		optionalSymbols[sym] = ptr
-		C.free(unsafe.Pointer(symStr))
+		symbols[sym] = lookupSymbol(libraryHandle, sym)
+	}
+
+	symbolsMutex.Lock()
+	defer symbolsMutex.Unlock()
+	optionalSymbols = symbols
+}
+
+// lookupSymbol returns the address of sym in the library at handle and its dependencies, or
+// in the whole binary if handle is nil
+func lookupSymbol(handle unsafe.Pointer, sym string) unsafe.Pointer {
+	symStr := C.CString(sym) // allocated by C; released by *1
+	// *1  release the symbol name
+	defer C.free(unsafe.Pointer(symStr))
+
+	return C._gogssapi_dlsym(handle, symStr)
+}
+
+// useLibrary looks for the optional symbols in the library at handle instead of the binary,
+// and applies the symbol maps again.  The C function pointers are not guarded, so it must be
+// called before any provider that uses the library is returned.
+func useLibrary(handle unsafe.Pointer) {
+	onceSymbols.Do(func() {})
+
+	libraryHandle = handle
+	readSymbols()
+
+	for _, m := range appliedSymbolMaps {
+		m.apply()
 	}
 }
 
 var onceSymbols = sync.Once{}
 
 func hasSymbol(sym string) bool {
-	onceSymbols.Do(readSymbols)
-	return optionalSymbols[sym] != nil
+	return librarySymbol(sym) != nil
 }
 
 func librarySymbol(sym string) unsafe.Pointer {
 	onceSymbols.Do(readSymbols)
+
+	symbolsMutex.RLock()
+	defer symbolsMutex.RUnlock()
 	return optionalSymbols[sym]
 }
 
@@ -58,10 +131,12 @@ type symbolMap map[string]**[0]byte
 
 // Set the C function pointer for each symbol in the map, to the location in memory of the symbol
 func (m *symbolMap) Apply() {
+	appliedSymbolMaps = append(appliedSymbolMaps, m)
+	m.apply()
+}
+
+func (m *symbolMap) apply() {
 	for sym, ptr := range *m {
-		funcPtr := librarySymbol(sym)
-		if funcPtr != nil {
-			*ptr = (*[0]byte)(funcPtr)
-		}
+		*ptr = (*[0]byte)(librarySymbol(sym))
 	}
 }
@@ -0,0 +1,297 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+/*
+#include "gss.h"
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+
+// The krb5 library is loaded as a dependency of the GSSAPI library; its credentials cache
+// routines are looked up at run time so that there is no build dependency on the krb5 headers.
+// The context and cache handles are opaque pointers in both MIT and Heimdal.
+int32_t (*__gogssapi_krb5_init_context)(void **ctx) = NULL;
+void (*__gogssapi_krb5_free_context)(void *ctx) = NULL;
+int32_t (*__gogssapi_krb5_cc_new_unique)(void *ctx, const char *type, const char *hint, void **cc) = NULL;
+int32_t (*__gogssapi_krb5_cc_resolve)(void *ctx, const char *name, void **cc) = NULL;
+int32_t (*__gogssapi_krb5_cc_get_full_name)(void *ctx, void *cc, char **name) = NULL;
+int32_t (*__gogssapi_krb5_cc_close)(void *ctx, void *cc) = NULL;
+int32_t (*__gogssapi_krb5_cc_destroy)(void *ctx, void *cc) = NULL;
+const char *(*__gogssapi_krb5_get_error_message)(void *ctx, int32_t code) = NULL;
+void (*__gogssapi_krb5_free_error_message)(void *ctx, const char *msg) = NULL;
+
+int _gogssapi_have_krb5_ccache() {
+	return __gogssapi_krb5_init_context != NULL && __gogssapi_krb5_free_context != NULL &&
+		__gogssapi_krb5_cc_new_unique != NULL && __gogssapi_krb5_cc_resolve != NULL &&
+		__gogssapi_krb5_cc_get_full_name != NULL && __gogssapi_krb5_cc_close != NULL &&
+		__gogssapi_krb5_cc_destroy != NULL;
+}
+
+// Create a new cache of the given type, returning its full name which must be freed by the caller
+static int32_t new_unique_ccache(const char *type, char **name) {
+	void *ctx = NULL;
+	void *cc = NULL;
+	int32_t code = __gogssapi_krb5_init_context(&ctx);
+	if( code != 0 ) {
+		return code;
+	}
+
+	code = __gogssapi_krb5_cc_new_unique(ctx, type, NULL, &cc);
+	if( code == 0 ) {
+		code = __gogssapi_krb5_cc_get_full_name(ctx, cc, name);
+		// closing the handle leaves MEMORY and KCM caches in place until they are destroyed
+		__gogssapi_krb5_cc_close(ctx, cc);
+	}
+
+	__gogssapi_krb5_free_context(ctx);
+	return code;
+}
+
+int32_t _gogssapi_krb5_new_unique_ccache(const char *type, char **name) {
+	return _GOGSSAPI_LOCKED(new_unique_ccache(type, name));
+}
+
+static int32_t destroy_ccache(const char *name) {
+	void *ctx = NULL;
+	void *cc = NULL;
+	int32_t code = __gogssapi_krb5_init_context(&ctx);
+	if( code != 0 ) {
+		return code;
+	}
+
+	code = __gogssapi_krb5_cc_resolve(ctx, name, &cc);
+	if( code == 0 ) {
+		code = __gogssapi_krb5_cc_destroy(ctx, cc);
+	}
+
+	__gogssapi_krb5_free_context(ctx);
+	return code;
+}
+
+int32_t _gogssapi_krb5_destroy_ccache(const char *name) {
+	return _GOGSSAPI_LOCKED(destroy_ccache(name));
+}
+
+// Return the description of a krb5 error code, which must be freed by the caller
+static char *error_message(int32_t code) {
+	void *ctx = NULL;
+	char *ret = NULL;
+	if( __gogssapi_krb5_get_error_message == NULL || __gogssapi_krb5_free_error_message == NULL ) {
+		return NULL;
+	}
+	if( __gogssapi_krb5_init_context(&ctx) != 0 ) {
+		return NULL;
+	}
+
+	const char *msg = __gogssapi_krb5_get_error_message(ctx, code);
+	if( msg != NULL ) {
+		ret = strdup(msg);
+		__gogssapi_krb5_free_error_message(ctx, msg);
+	}
+
+	__gogssapi_krb5_free_context(ctx);
+	return ret;
+}
+
+char *_gogssapi_krb5_error_message(int32_t code) {
+	return _GOGSSAPI_LOCKED(error_message(code));
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"unsafe"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// Map optional symbols from the krb5 library to the wrapper function pointers
+var krb5CCacheSymbols = symbolMap{
+	"krb5_init_context":       &C.__gogssapi_krb5_init_context,
+	"krb5_free_context":       &C.__gogssapi_krb5_free_context,
+	"krb5_cc_new_unique":      &C.__gogssapi_krb5_cc_new_unique,
+	"krb5_cc_resolve":         &C.__gogssapi_krb5_cc_resolve,
+	"krb5_cc_get_full_name":   &C.__gogssapi_krb5_cc_get_full_name,
+	"krb5_cc_close":           &C.__gogssapi_krb5_cc_close,
+	"krb5_cc_destroy":         &C.__gogssapi_krb5_cc_destroy,
+	"krb5_get_error_message":  &C.__gogssapi_krb5_get_error_message,
+	"krb5_free_error_message": &C.__gogssapi_krb5_free_error_message,
+}
+
+func init() {
+	krb5CCacheSymbols.Apply()
+}
+
+// Credentials cache types that can be used for private caches
+const (
+	// CCacheTypeMemory caches are held in the memory of the process
+	CCacheTypeMemory = "MEMORY"
+	// CCacheTypeKCM caches are held by the KCM daemon, if one is running
+	CCacheTypeKCM = "KCM"
+)
+
+func hasKrb5CCache() bool {
+	return C._gogssapi_have_krb5_ccache() == 1
+}
+
+func errNoKrb5CCache() error {
+	return makeCustomStatus(C.GSS_S_UNAVAILABLE, errors.New("the krb5 credentials cache routines are not available in this Kerberos library"))
+}
+
+func krb5Error(code C.int32_t) error {
+	err := MechError{MinorCode: uint32(code)}
+
+	cMsg := C._gogssapi_krb5_error_message(code) // allocated by C; released by *1
+	if cMsg != nil {
+		// *1  release the message
+		defer C.free(unsafe.Pointer(cMsg))
+		err.Message = C.GoString(cMsg)
+	} else {
+		err.Message = fmt.Sprintf("krb5 error %d", int32(code))
+	}
+
+	return err
+}
+
+// ownedCCache is a private credentials cache shared by a tenant credential and the credentials
+// added from it, which use the same cache.  It is destroyed when the last of them is released.
+type ownedCCache struct {
+	name string
+	refs atomic.Int32
+}
+
+func newOwnedCCache(name string) *ownedCCache {
+	o := &ownedCCache{name: name}
+	o.refs.Store(1)
+	return o
+}
+
+// ref records another credential using the cache, if there is one
+func (o *ownedCCache) ref() *ownedCCache {
+	if o != nil {
+		o.refs.Add(1)
+	}
+	return o
+}
+
+// release destroys the cache when the last credential using it is released
+func (o *ownedCCache) release() error {
+	if o == nil || o.refs.Add(-1) > 0 {
+		return nil
+	}
+	return DestroyCCache(o.name)
+}
+
+// NewUniqueCCache creates a new empty credentials cache of the type cctype, which is
+// normally CCacheTypeMemory or CCacheTypeKCM, and returns its full name.  Use the name
+// with the CredStoreCCache option to keep the credentials of one tenant or user apart from
+// all the others, instead of sharing the cache named by KRB5CCNAME.  The cache lasts until
+// DestroyCCache is called.
+func NewUniqueCCache(cctype string) (string, error) {
+	if err := loadDefaultLibrary(); err != nil {
+		return "", err
+	}
+	if !hasKrb5CCache() {
+		return "", errNoKrb5CCache()
+	}
+
+	cType := C.CString(cctype) // allocated by C; released by *1
+	defer C.free(unsafe.Pointer(cType))
+
+	var cName *C.char // allocated by krb5; released by *2
+	code := C._gogssapi_krb5_new_unique_ccache(cType, &cName)
+	if code != 0 {
+		return "", krb5Error(code)
+	}
+
+	// *2  release the name
+	defer C.free(unsafe.Pointer(cName))
+
+	return C.GoString(cName), nil
+}
+
+// DestroyCCache destroys a credentials cache and the credentials in it
+func DestroyCCache(name string) error {
+	if err := loadDefaultLibrary(); err != nil {
+		return err
+	}
+	if !hasKrb5CCache() {
+		return errNoKrb5CCache()
+	}
+
+	cName := C.CString(name) // allocated by C; released by *1
+	defer C.free(unsafe.Pointer(cName))
+
+	if code := C._gogssapi_krb5_destroy_ccache(cName); code != 0 {
+		return krb5Error(code)
+	}
+
+	return nil
+}
+
+// AcquireTenantCredential is like AcquireCredentialFrom but acquires the credential using a new
+// private credentials cache of type cctype, so that nothing is shared with other tenants.  Any
+// CredStoreCCache option is replaced by the new cache.  The cache is destroyed when the
+// returned credential, and any credential added from it with Add or AddFrom, has been
+// released.  The name of the cache is also returned.
+func AcquireTenantCredential(cctype string, name g.GssName, mechs []g.GssMech, usage g.CredUsage, lifetime *g.GssLifetime, opts ...g.CredStoreOption) (g.Credential, string, error) {
+	p, err := New()
+	if err != nil {
+		return nil, "", err
+	}
+
+	ccname, err := NewUniqueCCache(cctype)
+	if err != nil {
+		return nil, "", err
+	}
+
+	opts = append(slices.Clone(opts), g.WithCredStoreCCache(ccname))
+	cred, err := p.(g.ProviderExtCredStore).AcquireCredentialFrom(name, mechs, usage, lifetime, opts...)
+	if err != nil {
+		return nil, "", errors.Join(err, DestroyCCache(ccname))
+	}
+
+	cred.(*Credential).ownedCCache = newOwnedCCache(ccname)
+	return cred, ccname, nil
+}
+
+// StoreTenantCredential copies the Kerberos initiator credentials of cred, such as a delegated
+// credential, into a new private credentials cache of type cctype.  It returns a credential
+// backed by that cache and the name of the cache; the cache is destroyed when the returned
+// credential, and any credential added from it with Add or AddFrom, has been released.  cred is
+// left alone and must still be released by the caller.
+func StoreTenantCredential(cctype string, cred g.Credential) (g.Credential, string, error) {
+	lCred, ok := cred.(*Credential)
+	if !ok {
+		return nil, "", fmt.Errorf("bad credential type %T, %w", cred, g.ErrNoCred)
+	}
+
+	ccname, err := NewUniqueCCache(cctype)
+	if err != nil {
+		return nil, "", err
+	}
+
+	opt := g.WithCredStoreCCache(ccname)
+	_, _, err = lCred.StoreInto(g.GSS_MECH_KRB5, g.CredUsageInitiateOnly, true, false, opt)
+	if err != nil {
+		return nil, "", errors.Join(err, DestroyCCache(ccname))
+	}
+
+	p, err := New()
+	if err != nil {
+		return nil, "", err
+	}
+
+	tenantCred, err := p.(g.ProviderExtCredStore).AcquireCredentialFrom(nil, []g.GssMech{g.GSS_MECH_KRB5}, g.CredUsageInitiateOnly, nil, opt)
+	if err != nil {
+		return nil, "", errors.Join(err, DestroyCCache(ccname))
+	}
+
+	tenantCred.(*Credential).ownedCCache = newOwnedCCache(ccname)
+	return tenantCred, ccname, nil
+}
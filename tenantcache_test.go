@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"strings"
+	"testing"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+func TestNewUniqueCCache(t *testing.T) {
+	if !hasKrb5CCache() {
+		t.Log("skipping private ccache test because the krb5 ccache routines are not available")
+		t.SkipNow()
+	}
+
+	assert := NewAssert(t)
+
+	name1, err := NewUniqueCCache(CCacheTypeMemory)
+	assert.NoErrorFatal(err)
+	name2, err := NewUniqueCCache(CCacheTypeMemory)
+	assert.NoErrorFatal(err)
+
+	assert.True(strings.HasPrefix(name1, "MEMORY:"))
+	assert.NotEqual(name1, name2)
+
+	assert.NoError(DestroyCCache(name1))
+	assert.NoError(DestroyCCache(name2))
+
+	_, err = NewUniqueCCache("NOSUCHTYPE")
+	var mechErr MechError
+	assert.ErrorAs(err, &mechErr)
+	assert.NotEmpty(mechErr.Message)
+}
+
+func TestStoreTenantCredential(t *testing.T) {
+	if !hasKrb5CCache() || !ta.lib.HasExtension(g.HasExtCredStore) {
+		t.Log("skipping tenant credential test because the krb5 ccache routines or the CredStore extension are not available")
+		t.SkipNow()
+	}
+
+	assert := NewAssert(t)
+
+	ta.useAsset(t, testNoKeytab|testCredCache)
+
+	cred, err := ta.lib.AcquireCredential(nil, nil, g.CredUsageInitiateOnly, nil)
+	assert.NoErrorFatal(err)
+	defer cred.Release() //nolint:errcheck
+
+	tenant1, ccname1, err := StoreTenantCredential(CCacheTypeMemory, cred)
+	assert.NoErrorFatal(err)
+	tenant2, ccname2, err := StoreTenantCredential(CCacheTypeMemory, cred)
+	assert.NoErrorFatal(err)
+	defer tenant2.Release() //nolint:errcheck
+
+	assert.NotEqual(ccname1, ccname2)
+
+	info, err := tenant1.Inquire()
+	assert.NoErrorFatal(err)
+	assert.Equal("robot@GOLANG-AUTH.IO", info.Name)
+
+	// releasing the credential destroys its cache but not the other one
+	assert.NoError(tenant1.Release())
+
+	p := ta.lib.(g.ProviderExtCredStore)
+	_, err = p.AcquireCredentialFrom(nil, []g.GssMech{g.GSS_MECH_KRB5}, g.CredUsageInitiateOnly, nil, g.WithCredStoreCCache(ccname1))
+	assert.Error(err)
+
+	other, err := p.AcquireCredentialFrom(nil, []g.GssMech{g.GSS_MECH_KRB5}, g.CredUsageInitiateOnly, nil, g.WithCredStoreCCache(ccname2))
+	assert.NoError(err)
+	if other != nil {
+		_ = other.Release()
+	}
+}
+
+func TestAcquireTenantCredential(t *testing.T) {
+	if !hasKrb5CCache() || !ta.lib.HasExtension(g.HasExtCredStore) {
+		t.Log("skipping tenant credential test because the krb5 ccache routines or the CredStore extension are not available")
+		t.SkipNow()
+	}
+
+	assert := NewAssert(t)
+
+	ta.useAsset(t, testNoCredCache)
+
+	cred, ccname, err := AcquireTenantCredential(CCacheTypeMemory, nil, []g.GssMech{g.GSS_MECH_KRB5}, g.CredUsageAcceptOnly, nil,
+		g.WithCredStoreServerKeytab("FILE:"+ta.ktfileRack))
+	assert.NoErrorFatal(err)
+	assert.True(strings.HasPrefix(ccname, "MEMORY:"))
+	assert.NotNil(cred.(*Credential).ownedCCache)
+	assert.Equal(ccname, cred.(*Credential).ownedCCache.name)
+	assert.NoError(cred.Release())
+	assert.Nil(cred.(*Credential).ownedCCache)
+}
+
+func TestTenantCredentialAdd(t *testing.T) {
+	if !hasKrb5CCache() || !ta.lib.HasExtension(g.HasExtCredStore) {
+		t.Log("skipping tenant credential test because the krb5 ccache routines or the CredStore extension are not available")
+		t.SkipNow()
+	}
+	if isHeimdal() && !hasDuplicateCred() {
+		t.Skip("skipping test in this version of Heimdal")
+	}
+
+	assert := NewAssert(t)
+
+	ta.useAsset(t, testNoKeytab|testCredCache)
+
+	cred, err := ta.lib.AcquireCredential(nil, nil, g.CredUsageInitiateOnly, nil)
+	assert.NoErrorFatal(err)
+	defer cred.Release() //nolint:errcheck
+
+	tenant, ccname, err := StoreTenantCredential(CCacheTypeMemory, cred)
+	assert.NoErrorFatal(err)
+
+	// a credential added from the tenant credential uses the same cache
+	added, err := tenant.Add(nil, g.GSS_MECH_IAKERB, g.CredUsageInitiateOnly, nil, nil, false)
+	assert.NoErrorFatal(err)
+
+	// so the cache outlives the tenant credential
+	assert.NoError(tenant.Release())
+
+	p := ta.lib.(g.ProviderExtCredStore)
+	other, err := p.AcquireCredentialFrom(nil, []g.GssMech{g.GSS_MECH_KRB5}, g.CredUsageInitiateOnly, nil, g.WithCredStoreCCache(ccname))
+	assert.NoError(err)
+	if other != nil {
+		_ = other.Release()
+	}
+
+	// and is destroyed along with the last credential using it
+	assert.NoError(added.Release())
+
+	_, err = p.AcquireCredentialFrom(nil, []g.GssMech{g.GSS_MECH_KRB5}, g.CredUsageInitiateOnly, nil, g.WithCredStoreCCache(ccname))
+	assert.Error(err)
+}
@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+/*
+#include "gss.h"
+
+// krb5_boolean is an unsigned int in both MIT and Heimdal
+unsigned int (*__gogssapi_krb5_is_thread_safe)(void) = NULL;
+
+// Returns 1 if the krb5 library is thread safe, 0 if not, or -1 if it does not say
+int _gogssapi_krb5_is_thread_safe() {
+	if( __gogssapi_krb5_is_thread_safe == NULL ) {
+		return -1;
+	}
+	return __gogssapi_krb5_is_thread_safe() ? 1 : 0;
+}
+*/
+import "C"
+
+import (
+	"os"
+	"sync"
+)
+
+// Map optional symbols from the GSSAPI library to the wrapper function pointers
+var threadSafetySymbols = symbolMap{
+	"krb5_is_thread_safe": &C.__gogssapi_krb5_is_thread_safe,
+}
+
+func init() {
+	threadSafetySymbols.Apply()
+	updateSerializeCalls()
+}
+
+// ThreadSafety reports whether the krb5 library says it is thread safe
+type ThreadSafety int
+
+const (
+	// The library does not export krb5_is_thread_safe
+	ThreadSafetyUnknown ThreadSafety = iota
+	ThreadSafe
+	NotThreadSafe
+)
+
+func (t ThreadSafety) String() string {
+	switch t {
+	case ThreadSafe:
+		return "thread safe"
+	case NotThreadSafe:
+		return "not thread safe"
+	default:
+		return "unknown"
+	}
+}
+
+// SerializeCallsEnv is the environment variable that overrides whether calls into the GSSAPI
+// library are serialized: 1 serializes them and 0 lets them run concurrently, whatever the
+// library reports.
+const SerializeCallsEnv = "GOGSSAPI_SERIALIZE_CALLS"
+
+var (
+	serializeMutex    sync.Mutex
+	serializeOverride *bool
+	serializing       bool
+)
+
+// SetSerializeCalls overrides the library's krb5_is_thread_safe, for builds that are known to
+// be unsafe despite what they report, or known to be safe without reporting it.  When calls
+// are serialized, every call into the GSSAPI and krb5 libraries waits for a global lock.
+// Calls already running when serialization is turned on are not waited for, so this should
+// be called before the provider is used.
+func SetSerializeCalls(serialize bool) {
+	serializeMutex.Lock()
+	defer serializeMutex.Unlock()
+
+	serializeOverride = &serialize
+	setSerializing(serialize)
+}
+
+// SerializingCalls reports whether calls into the GSSAPI library are being serialized
+func SerializingCalls() bool {
+	serializeMutex.Lock()
+	defer serializeMutex.Unlock()
+
+	return serializing
+}
+
+// updateSerializeCalls decides whether to serialize calls, after the library has been found
+func updateSerializeCalls() {
+	serializeMutex.Lock()
+	defer serializeMutex.Unlock()
+
+	switch {
+	case serializeOverride != nil:
+		setSerializing(*serializeOverride)
+	case os.Getenv(SerializeCallsEnv) == "1":
+		setSerializing(true)
+	case os.Getenv(SerializeCallsEnv) == "0":
+		setSerializing(false)
+	default:
+		setSerializing(libraryThreadSafety() == NotThreadSafe)
+	}
+}
+
+func setSerializing(serialize bool) {
+	serializing = serialize
+	if serialize {
+		C._gogssapi_set_serialize_calls(1)
+	} else {
+		C._gogssapi_set_serialize_calls(0)
+	}
+}
+
+func libraryThreadSafety() ThreadSafety {
+	switch C._gogssapi_krb5_is_thread_safe() {
+	case 1:
+		return ThreadSafe
+	case 0:
+		return NotThreadSafe
+	default:
+		return ThreadSafetyUnknown
+	}
+}
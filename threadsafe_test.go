@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+func TestSetSerializeCalls(t *testing.T) {
+	assert := NewAssert(t)
+	defer resetSerializeCalls()
+
+	SetSerializeCalls(true)
+	assert.True(SerializingCalls())
+	assert.True(ta.lib.(*provider).Info().SerializedCalls)
+
+	// the override survives the library being looked at again
+	updateSerializeCalls()
+	assert.True(SerializingCalls())
+
+	SetSerializeCalls(false)
+	assert.False(SerializingCalls())
+
+	t.Setenv(SerializeCallsEnv, "1")
+	resetSerializeCalls()
+	assert.True(SerializingCalls())
+
+	t.Setenv(SerializeCallsEnv, "")
+	updateSerializeCalls()
+	assert.Equal(libraryThreadSafety() == NotThreadSafe, SerializingCalls())
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	if testing.Short() {
+		t.Log("skipping concurrent calls stress test in short mode")
+		t.SkipNow()
+	}
+
+	ta.useAsset(t, testCredCache|testKeytabRack)
+	defer resetSerializeCalls()
+
+	for _, serialize := range []bool{false, true} {
+		t.Run(fmt.Sprintf("serialize=%v", serialize), func(t *testing.T) {
+			SetSerializeCalls(serialize)
+
+			const goroutines = 16
+			const rounds = 10
+
+			var wg sync.WaitGroup
+			errs := make(chan error, goroutines)
+			for range goroutines {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for range rounds {
+						if err := stressOneContext(); err != nil {
+							errs <- err
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// stressOneContext acquires a credential, establishes a context and exchanges a message
+func stressOneContext() error {
+	cred, err := ta.lib.AcquireCredential(nil, nil, g.CredUsageInitiateOnly, nil)
+	if err != nil {
+		return err
+	}
+	defer cred.Release() //nolint:errcheck
+
+	name, err := ta.lib.ImportName("rack@foo.golang-auth.io", g.GSS_NT_HOSTBASED_SERVICE)
+	if err != nil {
+		return err
+	}
+	defer name.Release() //nolint:errcheck
+
+	initiator, err := ta.lib.InitSecContext(name, g.WithInitiatorCredential(cred), g.WithInitiatorFlags(g.ContextFlagMutual))
+	if err != nil {
+		return err
+	}
+	defer initiator.Delete() //nolint:errcheck
+
+	acceptor, err := ta.lib.AcceptSecContext()
+	if err != nil {
+		return err
+	}
+	defer acceptor.Delete() //nolint:errcheck
+
+	var initiatorTok, acceptorTok []byte
+	for initiator.ContinueNeeded() || acceptor.ContinueNeeded() {
+		acceptorTok, _, err = initiator.Continue(initiatorTok)
+		if err != nil {
+			return err
+		}
+
+		if len(acceptorTok) > 0 {
+			initiatorTok, _, err = acceptor.Continue(acceptorTok)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	msg := []byte("Hello GSSAPI")
+	wrapped, _, err := initiator.Wrap(msg, true, 0)
+	if err != nil {
+		return err
+	}
+	unwrapped, _, _, err := acceptor.Unwrap(wrapped)
+	if err != nil {
+		return err
+	}
+	if string(unwrapped) != string(msg) {
+		return fmt.Errorf("unwrapped %q, expected %q", unwrapped, msg)
+	}
+
+	return nil
+}
+
+func resetSerializeCalls() {
+	serializeMutex.Lock()
+	serializeOverride = nil
+	serializeMutex.Unlock()
+
+	updateSerializeCalls()
+}
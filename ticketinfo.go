@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+/*
+#include "gss.h"
+#include <stdint.h>
+#include <time.h>
+
+// The function pointers are set to the library functions by the init function using symbolMap.Apply()
+OM_uint32 (*__gogssapi_extract_authtime)(OM_uint32 *minor_status, gss_ctx_id_t context_handle, void *authtime) = NULL;
+OM_uint32 (*__gogssapi_get_tkt_flags)(OM_uint32 *minor_status, gss_ctx_id_t context_handle, uint32_t *tkt_flags) = NULL;
+OM_uint32 (*__gogssapi_extract_authz_data)(OM_uint32 *minor_status, gss_ctx_id_t context_handle, int ad_type, gss_buffer_t ad_data) = NULL;
+
+// MIT returns the authtime as a 32 bit krb5_timestamp and Heimdal as a time_t
+static OM_uint32 extract_authtime(OM_uint32 *minor_status, gss_ctx_id_t context_handle, int64_t *authtime) {
+	OM_uint32 major;
+	if( is_heimdal() ) {
+		time_t t = 0;
+		major = __gogssapi_extract_authtime(minor_status, context_handle, &t);
+		*authtime = (int64_t)t;
+	} else {
+		uint32_t t = 0;
+		major = __gogssapi_extract_authtime(minor_status, context_handle, &t);
+		*authtime = (int64_t)t;
+	}
+	return major;
+}
+
+OM_uint32 _gogssapi_extract_authtime(OM_uint32 *minor_status, gss_ctx_id_t context_handle, int64_t *authtime) {
+	if( __gogssapi_extract_authtime == NULL ) {
+		*minor_status = 0;
+		return GSS_S_UNAVAILABLE;
+	}
+	return _GOGSSAPI_LOCKED(extract_authtime(minor_status, context_handle, authtime));
+}
+
+OM_uint32 _gogssapi_get_tkt_flags(OM_uint32 *minor_status, gss_ctx_id_t context_handle, uint32_t *tkt_flags) {
+	if( __gogssapi_get_tkt_flags == NULL ) {
+		*minor_status = 0;
+		return GSS_S_UNAVAILABLE;
+	}
+	return _GOGSSAPI_LOCKED(__gogssapi_get_tkt_flags(minor_status, context_handle, tkt_flags));
+}
+
+OM_uint32 _gogssapi_extract_authz_data(OM_uint32 *minor_status, gss_ctx_id_t context_handle, int ad_type, gss_buffer_t ad_data) {
+	if( __gogssapi_extract_authz_data == NULL ) {
+		*minor_status = 0;
+		return GSS_S_UNAVAILABLE;
+	}
+	return _GOGSSAPI_LOCKED(__gogssapi_extract_authz_data(minor_status, context_handle, ad_type, ad_data));
+}
+*/
+import "C"
+
+import (
+	"math/bits"
+	"time"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// Map optional symbols from the GSSAPI library to the wrapper function pointers
+var ticketInfoSymbols = symbolMap{
+	"gsskrb5_extract_authtime_from_sec_context":   &C.__gogssapi_extract_authtime,
+	"gss_krb5_get_tkt_flags":                      &C.__gogssapi_get_tkt_flags,
+	"gsskrb5_extract_authz_data_from_sec_context": &C.__gogssapi_extract_authz_data,
+}
+
+func init() {
+	ticketInfoSymbols.Apply()
+}
+
+// AuthTime returns the time of the initial authentication of the client, from the Kerberos
+// ticket used to establish an acceptor context.  It allows a maximum authentication age
+// to be enforced.
+func (c *SecContext) AuthTime() (time.Time, error) {
+	var cMinor C.OM_uint32
+	var cAuthtime C.int64_t
+	cMajor := C._gogssapi_extract_authtime(&cMinor, c.id, &cAuthtime)
+	if cMajor != C.GSS_S_COMPLETE {
+		return time.Time{}, makeMechStatus(cMajor, cMinor, g.GSS_MECH_KRB5)
+	}
+
+	return time.Unix(int64(cAuthtime), 0), nil
+}
+
+// TicketFlags returns the flags of the Kerberos ticket used to establish an acceptor context
+func (c *SecContext) TicketFlags() (TicketFlags, error) {
+	var cMinor C.OM_uint32
+	var cFlags C.uint32_t
+	cMajor := C._gogssapi_get_tkt_flags(&cMinor, c.id, &cFlags)
+	if cMajor != C.GSS_S_COMPLETE {
+		return 0, makeMechStatus(cMajor, cMinor, g.GSS_MECH_KRB5)
+	}
+
+	flags := uint32(cFlags)
+	if isHeimdal() {
+		flags = heimdalTicketFlags(flags)
+	}
+
+	return TicketFlags(flags), nil
+}
+
+// heimdalTicketFlags converts flags from Heimdal's TicketFlags2int, which numbers the bits of
+// the ASN.1 bit string from the least significant end, to the krb5 library representation
+func heimdalTicketFlags(flags uint32) uint32 {
+	return bits.Reverse32(flags)
+}
+
+// PAC returns the raw AD-WIN2K-PAC authorization data from the Kerberos ticket used to
+// establish an acceptor context, as issued by Active Directory and recent MIT and Heimdal KDCs
+func (c *SecContext) PAC() ([]byte, error) {
+	var cMinor C.OM_uint32
+	var cData C.gss_buffer_desc = C.gss_empty_buffer // allocated by GSSAPI; released by *1
+	cMajor := C._gogssapi_extract_authz_data(&cMinor, c.id, AuthzDataWin2kPAC, &cData)
+	if cMajor != C.GSS_S_COMPLETE {
+		return nil, makeMechStatus(cMajor, cMinor, g.GSS_MECH_KRB5)
+	}
+
+	defer C.gss_release_buffer(&cMinor, &cData) // *1  Release GSSAPI allocated buffer
+
+	return C.GoBytes(cData.value, C.int(cData.length)), nil
+}
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"testing"
+	"time"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+func TestHeimdalTicketFlags(t *testing.T) {
+	assert := NewAssert(t)
+
+	// forwardable is bit 1 and ok-as-delegate bit 13 of the Heimdal representation
+	flags := TicketFlags(heimdalTicketFlags(1<<1 | 1<<13))
+	assert.Equal(TicketFlagForwardable|TicketFlagOKAsDelegate, flags)
+	assert.Equal("FO", flags.String())
+}
+
+func TestAcceptorTicketInfo(t *testing.T) {
+	assert := NewAssert(t)
+
+	_, acceptor := establishContexts(t, g.ContextFlagMutual)
+
+	if hasSymbol("gsskrb5_extract_authtime_from_sec_context") {
+		authtime, err := acceptor.AuthTime()
+		assert.NoError(err)
+		assert.True(authtime.Before(time.Now().Add(5 * time.Minute)))
+		assert.True(authtime.After(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)))
+	}
+
+	if hasSymbol("gss_krb5_get_tkt_flags") {
+		flags, err := acceptor.TicketFlags()
+		assert.NoError(err)
+		assert.Zero(flags & TicketFlagInvalid)
+		assert.Zero(flags & TicketFlagInitial) // a service ticket, not a TGT
+	}
+
+	if hasSymbol("gsskrb5_extract_authz_data_from_sec_context") {
+		// KDCs before MIT 1.20 do not issue PACs
+		pac, err := acceptor.PAC()
+		if err == nil {
+			decoded, err := DecodePAC(pac)
+			assert.NoError(err)
+			if assert.NotNil(decoded) {
+				assert.NotNil(decoded.ServerChecksum)
+			}
+		}
+	}
+}
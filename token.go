@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"bytes"
+	"fmt"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+// tokenFramingTag is the [APPLICATION 0] tag that introduces the mechanism-independent
+// token framing of RFC 2743 § 3.1
+const tokenFramingTag = 0x60
+
+// splitFramedToken removes the RFC 2743 § 3.1 framing from a token, returning the mechanism
+// OID and the inner, mechanism specific, token.
+func splitFramedToken(token []byte) (g.Oid, []byte, error) {
+	if len(token) == 0 || token[0] != tokenFramingTag {
+		return nil, nil, fmt.Errorf("%w: missing token framing", ErrMalformedToken)
+	}
+
+	outer, rest, err := readDER(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) > 0 {
+		return nil, nil, fmt.Errorf("%w: %d bytes of trailing data after token", ErrMalformedToken, len(rest))
+	}
+
+	oid, inner, err := expectDER(outer.body, derClassUniversal, derTagOID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(oid.body) == 0 {
+		return nil, nil, fmt.Errorf("%w: empty mechanism OID", ErrMalformedToken)
+	}
+
+	return g.Oid(oid.body), inner, nil
+}
+
+// ntlmsspSignature starts every raw NTLMSSP message (MS-NLMP § 2.2)
+var ntlmsspSignature = []byte("NTLMSSP\x00")
+
+// TokenHeader describes the mechanism used by an initial context token.
+type TokenHeader struct {
+	// MechOid is the mechanism OID from the token header
+	MechOid g.Oid
+	// MechOidString is the dotted string form of MechOid
+	MechOidString string
+	// Mech is the mechanism corresponding to MechOid: one known to go-gssapi, a registered
+	// mechanism such as GSS_MECH_NTLMSSP, or else an opaque Mech named after the OID
+	Mech g.GssMech
+	// Framed is false for raw NTLMSSP tokens, which are sent without the RFC 2743 framing
+	Framed bool
+	// InnerToken is the mechanism specific token that follows the header
+	InnerToken []byte
+}
+
+// ParseTokenHeader decodes the RFC 2743 § 3.1 header of the first token sent by an initiator
+// (the InitialContextToken).  An acceptor can use it to learn the mechanism before calling
+// AcceptSecContext, to pick a credential or to reject the mechanism outright.  Raw NTLMSSP
+// tokens, which some clients send without any framing, are reported with Framed set to false.
+//
+// ErrMalformedToken is returned if the header cannot be decoded.  Subsequent context tokens
+// do not carry the header and cannot be identified this way.
+func ParseTokenHeader(token []byte) (*TokenHeader, error) {
+	if bytes.HasPrefix(token, ntlmsspSignature) {
+		return &TokenHeader{
+			MechOid:       GSS_MECH_NTLMSSP.Oid(),
+			MechOidString: GSS_MECH_NTLMSSP.OidString(),
+			Mech:          GSS_MECH_NTLMSSP,
+			InnerToken:    token,
+		}, nil
+	}
+
+	oid, inner, err := splitFramedToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	oidString, err := oid2String(oid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad mechanism OID: %w", ErrMalformedToken, err)
+	}
+
+	ret := &TokenHeader{
+		MechOid:       oid,
+		MechOidString: oidString,
+		Framed:        true,
+		InnerToken:    inner,
+	}
+
+	ret.Mech, err = mechFromOid(oid)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gssapi
+
+import (
+	"bytes"
+	"testing"
+
+	g "github.com/golang-auth/go-gssapi/v3"
+)
+
+func TestParseTokenHeader(t *testing.T) {
+	assert := NewAssert(t)
+
+	inner := []byte{0x01, 0x00, 0x6e, 0x00}
+
+	hdr, err := ParseTokenHeader(derFramed(g.GSS_MECH_KRB5.Oid(), inner))
+	assert.NoErrorFatal(err)
+	assert.True(hdr.Framed)
+	assert.Equal(g.GSS_MECH_KRB5, hdr.Mech)
+	assert.Equal(g.GSS_MECH_KRB5.Oid(), hdr.MechOid)
+	assert.Equal("1.2.840.113554.1.2.2", hdr.MechOidString)
+	assert.Equal(inner, hdr.InnerToken)
+
+	hdr, err = ParseTokenHeader(derFramed(g.GSS_MECH_SPNEGO.Oid(), derCtx(0)))
+	assert.NoErrorFatal(err)
+	assert.Equal(g.GSS_MECH_SPNEGO, hdr.Mech)
+
+	// mechanism registered by the provider
+	hdr, err = ParseTokenHeader(derFramed(GSS_MECH_NEGOEX.Oid(), inner))
+	assert.NoErrorFatal(err)
+	assert.Equal(GSS_MECH_NEGOEX, hdr.Mech)
+	assert.Equal("1.3.6.1.4.1.311.2.2.30", hdr.MechOidString)
+
+	// unknown mechanism
+	hdr, err = ParseTokenHeader(derFramed(g.Oid{0x2b, 0x06, 0x01, 0x04, 0x01, 0x82, 0x37, 0x02, 0x02, 0x7f}, inner))
+	assert.NoErrorFatal(err)
+	assert.Equal("1.3.6.1.4.1.311.2.2.127", hdr.MechOidString)
+	assert.Equal(hdr.MechOidString, hdr.Mech.String())
+	assert.Equal(hdr.MechOid, hdr.Mech.Oid())
+
+	// raw NTLM
+	ntlm := append([]byte("NTLMSSP\x00"), 0x01, 0x00, 0x00, 0x00)
+	hdr, err = ParseTokenHeader(ntlm)
+	assert.NoErrorFatal(err)
+	assert.False(hdr.Framed)
+	assert.Equal(GSS_MECH_NTLMSSP, hdr.Mech)
+	assert.Equal(GSS_MECH_NTLMSSP.Oid(), hdr.MechOid)
+	assert.Equal("1.3.6.1.4.1.311.2.2.10", hdr.MechOidString)
+	assert.Equal(ntlm, hdr.InnerToken)
+}
+
+func TestParseTokenHeaderMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		token []byte
+	}{
+		{"empty", nil},
+		{"no framing", []byte{0x30, 0x03, 0x06, 0x01, 0x00}},
+		{"tag only", []byte{0x60}},
+		{"length too long", []byte{0x60, 0x10, 0x06, 0x01, 0x2a}},
+		{"truncated long length", []byte{0x60, 0x82, 0x01}},
+		{"indefinite length", []byte{0x60, 0x80, 0x06, 0x01, 0x2a, 0x00, 0x00}},
+		{"huge length", []byte{0x60, 0x84, 0xff, 0xff, 0xff, 0xff, 0x06}},
+		{"too many length octets", []byte{0x60, 0x85, 0x00, 0x00, 0x00, 0x00, 0x03, 0x06, 0x01, 0x2a}},
+		{"trailing data", append(derFramed(g.GSS_MECH_KRB5.Oid()), 0x00)},
+		{"no oid", derTLV(0x60, derOctets([]byte{0x01}))},
+		{"empty oid", derTLV(0x60, derTLV(0x06))},
+		{"oid overruns", []byte{0x60, 0x04, 0x06, 0x05, 0x2a, 0x86}},
+		{"bad oid", derFramed(g.Oid{0x2a, 0x86})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert := NewAssert(t)
+			_, err := ParseTokenHeader(tt.token)
+			assert.ErrorIs(err, ErrMalformedToken)
+		})
+	}
+}
+
+func FuzzParseTokenHeader(f *testing.F) {
+	f.Add(derFramed(g.GSS_MECH_KRB5.Oid(), []byte{0x01, 0x00}))
+	f.Add(derFramed(g.GSS_MECH_SPNEGO.Oid(), derCtx(0, derSeq())))
+	f.Add([]byte{0x60, 0x84, 0x7f, 0xff, 0xff, 0xff, 0x06, 0x01, 0x2a})
+	f.Add([]byte{0x60, 0x81, 0x05, 0x06, 0x01})
+	f.Add([]byte("NTLMSSP\x00\x01"))
+
+	f.Fuzz(func(t *testing.T, token []byte) {
+		hdr, err := ParseTokenHeader(token)
+		if err != nil {
+			return
+		}
+
+		if !bytes.HasSuffix(token, hdr.InnerToken) {
+			t.Errorf("inner token is not a suffix of the input")
+		}
+		if hdr.Framed && !bytes.Contains(token, hdr.MechOid) {
+			t.Errorf("mechanism OID is not part of the input")
+		}
+	})
+}